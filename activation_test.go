@@ -0,0 +1,83 @@
+package main_test
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Activation", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+	})
+
+	It("Can set activations per layer and neuron", func() {
+		n, err := gc.MLPWith([]uint{2, 2, 1},
+			gc.WithActFns(grad.ReluActFn, grad.LinearActFn),
+			gc.WithNeuronActFn(0, 1, grad.TanhActFn))
+		Expect(err).ToNot(HaveOccurred())
+
+		// Both hidden neurons get -2 and the output adds the first to ten
+		// times the second
+		Expect(n.LoadStateDict(grad.StateDict[float64]{
+			"layers.0.neurons.0.b":  0,
+			"layers.0.neurons.0.w0": -2,
+			"layers.0.neurons.0.w1": 0,
+			"layers.0.neurons.1.b":  0,
+			"layers.0.neurons.1.w0": -2,
+			"layers.0.neurons.1.w1": 0,
+			"layers.1.neurons.0.b":  0,
+			"layers.1.neurons.0.w0": 1,
+			"layers.1.neurons.0.w1": 10,
+		})).To(Succeed())
+
+		out := n.Forward(gc.Vals(1, -1))[0]
+		Expect(out.Op()).To(Equal(grad.OpAdd))
+
+		// The first neuron kept the layer's relu, the second uses tanh
+		Expect(out.Data()).To(BeNumerically("~", 10*math.Tanh(-2), 1e-12))
+	})
+
+	It("Can use a custom activation", func() {
+		softplus := grad.ActFn("softplus")
+		Expect(gc.RegisterActFn(softplus, func(x *grad.Value[float64], withArgs ...grad.ValueArg[float64]) *grad.Value[float64] {
+			return x.Exp().Add(gc.Val(1), withArgs...)
+		})).To(Succeed())
+
+		n, err := gc.MLPWith([]uint{1, 1}, gc.WithActFns(softplus))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n.LoadStateDict(grad.StateDict[float64]{
+			"layers.0.neurons.0.b":  0,
+			"layers.0.neurons.0.w0": 1,
+		})).To(Succeed())
+
+		out := n.Forward(gc.Vals(0.5))[0]
+		Expect(out.Label()).To(Equal("out"))
+		Expect(out.Data()).To(BeNumerically("~", math.Exp(0.5)+1))
+	})
+
+	It("Rejects unknown activations at construction", func() {
+		_, err := gc.MLPWith([]uint{2, 1}, gc.WithActFns("sigmoid"))
+		Expect(err).To(MatchError(grad.ErrUnknownActFn))
+
+		_, err = gc.MLPWith([]uint{2, 2, 1}, gc.WithActFns(grad.ReluActFn))
+		Expect(err).To(HaveOccurred())
+
+		_, err = gc.MLPWith([]uint{2, 2, 1}, gc.WithNeuronActFn(0, 2, grad.ReluActFn))
+		Expect(err).To(HaveOccurred())
+
+		n := gc.MLP(2, 1)
+		Expect(n.SetActFn(0, "sigmoid")).To(MatchError(grad.ErrUnknownActFn))
+	})
+
+	It("Does not allow builtin activations to be replaced", func() {
+		err := gc.RegisterActFn(grad.ReluActFn, func(x *grad.Value[float64], withArgs ...grad.ValueArg[float64]) *grad.Value[float64] {
+			return x
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
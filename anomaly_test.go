@@ -0,0 +1,62 @@
+package main_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Anomaly detection", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		gc.SetDetectAnomaly(true)
+	})
+
+	It("Finds the origin of a NaN in the forward pass", func() {
+		x := gc.Val(-2, gc.WithLabel("x"))
+		y := x.Pow(0.5, gc.WithLabel("sqrt"))
+		loss := y.Add(gc.Val(1), gc.WithLabel("loss"))
+
+		Expect(gc.Anomaly()).To(MatchError(grad.ErrAnomaly))
+
+		err := gc.Backward(loss)
+		Expect(err).To(MatchError(grad.ErrAnomaly))
+
+		var aerr *grad.AnomalyError
+		Expect(errors.As(err, &aerr)).To(BeTrue())
+		Expect(aerr.Phase).To(Equal("forward"))
+		Expect(aerr.Node.ID).To(Equal(y.ID()))
+		Expect(aerr.Node.Op).To(Equal(grad.OpPow))
+		Expect(aerr.Node.Label).To(Equal("sqrt"))
+		Expect(aerr.Path).To(HaveLen(2))
+		Expect(aerr.Path[0].Label).To(Equal("loss"))
+		Expect(err.Error()).To(ContainSubstring(`label "sqrt"`))
+	})
+
+	It("Finds infinite gradients in the backward pass", func() {
+		x := gc.Val(0, gc.WithLabel("x"))
+		y := x.Pow(2, gc.WithLabel("y"))
+		z := y.Pow(0.5, gc.WithLabel("z"))
+
+		Expect(gc.Anomaly()).ToNot(HaveOccurred())
+
+		err := gc.Backward(z)
+		var aerr *grad.AnomalyError
+		Expect(errors.As(err, &aerr)).To(BeTrue())
+		Expect(aerr.Phase).To(Equal("backward"))
+		Expect(aerr.Node.ID).To(Equal(z.ID()))
+	})
+
+	It("Does not check when turned off", func() {
+		gc.SetDetectAnomaly(false)
+		x := gc.Val(-2)
+		y := x.Pow(0.5)
+
+		Expect(gc.Anomaly()).ToNot(HaveOccurred())
+		Expect(gc.Backward(y)).To(Succeed())
+	})
+})
@@ -0,0 +1,97 @@
+package main_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gonum.org/v1/plot/vg"
+
+	"github.com/richiejp/micrograd/internal/grad"
+	"github.com/richiejp/micrograd/internal/viz"
+)
+
+var _ = Describe("Decision boundary", func() {
+	var gc *grad.Context[float64]
+	X := [][]float64{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		gc.Seed(1)
+	})
+
+	It("Saves plots in several formats", func() {
+		model := gc.MLP(2, 4, 1)
+		y := []int{0, 1, 1, 0}
+		dir := GinkgoT().TempDir()
+
+		for _, name := range []string{"boundary.png", "boundary.svg", "boundary.pdf"} {
+			path := filepath.Join(dir, name)
+			Expect(viz.DecisionBoundary(path, gc, model, X, y, viz.BoundaryOptions{Resolution: 10})).To(Succeed())
+
+			info, err := os.Stat(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size()).To(BeNumerically(">", 0))
+		}
+	})
+
+	It("Plots multiple classes", func() {
+		model := gc.MLP(2, 4, 3)
+		y := []int{0, 1, 2, 1}
+
+		p, err := viz.BoundaryPlot(gc, model, X, y, viz.BoundaryOptions{Resolution: 5})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Title.Text).To(Equal("Decision boundary"))
+
+		// One legend entry for each class of points
+		wt, err := p.WriterTo(4*vg.Inch, 4*vg.Inch, "svg")
+		Expect(err).ToNot(HaveOccurred())
+		var svg strings.Builder
+		_, err = wt.WriteTo(&svg)
+		Expect(err).ToNot(HaveOccurred())
+		for class := range 3 {
+			Expect(svg.String()).To(ContainSubstring(fmt.Sprintf(">Class %d<", class)))
+		}
+		Expect(strings.Count(svg.String(), ">Class ")).To(Equal(3))
+	})
+
+	It("Plots in evaluation mode and restores the previous mode", func() {
+		dropout := must(gc.Dropout(0.5))
+		bn := gc.BatchNorm(8)
+		model := gc.Seq(gc.Lay(2, 8), dropout, bn, gc.Lay(8, 1))
+		y := []int{0, 1, 1, 0}
+		stats := model.StateDict()
+
+		plot := func() string {
+			var svg strings.Builder
+			Expect(viz.WriteBoundary(&svg, "svg", gc, model, X, y, viz.BoundaryOptions{Resolution: 10})).To(Succeed())
+			return svg.String()
+		}
+
+		// Dropout would give a different boundary each time
+		first := plot()
+		Expect(plot()).To(Equal(first))
+		Expect(model.Training()).To(BeTrue())
+		Expect(stats.Diff(model.StateDict(), 0)).To(BeEmpty())
+
+		model.SetTraining(false)
+		Expect(plot()).To(Equal(first))
+		Expect(dropout.Training()).To(BeFalse())
+		Expect(bn.Training()).To(BeFalse())
+	})
+
+	It("Rejects negative labels", func() {
+		model := gc.MLP(2, 1)
+		_, err := viz.BoundaryPlot(gc, model, X, []int{-1, 1, -1, 1}, viz.BoundaryOptions{Resolution: 5})
+		Expect(err).To(MatchError(ContainSubstring("label -1")))
+	})
+
+	It("Rejects points which are not 2D", func() {
+		model := gc.MLP(3, 1)
+		_, err := viz.BoundaryPlot(gc, model, [][]float64{{1, 2, 3}}, []int{0}, viz.BoundaryOptions{})
+		Expect(err).To(MatchError(ContainSubstring("3 dimensions")))
+	})
+})
@@ -0,0 +1,145 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/cli"
+)
+
+var _ = Describe("CLI", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	run := func(stdin string, args ...string) (string, string, error) {
+		var stdout, stderr strings.Builder
+		err := cli.Run(args, strings.NewReader(stdin), &stdout, &stderr)
+		return stdout.String(), stderr.String(), err
+	}
+
+	It("Lists the commands", func() {
+		_, stderr, err := run("")
+		Expect(err).To(MatchError(cli.ErrUsage))
+		Expect(stderr).To(ContainSubstring("usage: micrograd <command>"))
+		for _, c := range []string{"train", "eval", "predict", "graph", "search", "dataset"} {
+			Expect(stderr).To(ContainSubstring("\n  " + c + " "))
+		}
+
+		stdout, _, err := run("", "help")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("dataset"))
+
+		_, stderr, err = run("", "fly")
+		Expect(err).To(MatchError(cli.ErrUsage))
+		Expect(stderr).To(ContainSubstring(`unknown command "fly"`))
+	})
+
+	It("Shows help and rejects bad flags", func() {
+		_, stderr, err := run("", "train", "-h")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("-optimizer"))
+
+		_, _, err = run("", "train", "-epochs", "many")
+		Expect(err).To(MatchError(cli.ErrUsage))
+
+		_, stderr, err = run("", "train", "-act", "sigmoid")
+		Expect(err).To(MatchError(cli.ErrUsage))
+		Expect(stderr).To(ContainSubstring(`unknown activation "sigmoid"`))
+
+		_, _, err = run("", "dataset", "-dataset", "iris")
+		Expect(err).To(MatchError(cli.ErrUsage))
+	})
+
+	It("Runs extra commands", func() {
+		var got []string
+		var stdout, stderr strings.Builder
+		err := cli.Run([]string{"demo", "-x"}, nil, &stdout, &stderr, cli.Command{
+			Name: "demo",
+			Run: func(env *cli.Env, args []string) error {
+				got = args
+				return nil
+			},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(got).To(Equal([]string{"-x"}))
+	})
+
+	It("Generates datasets", func() {
+		stdout, _, err := run("", "dataset", "-dataset", "blobs", "-samples", "6", "-classes", "3")
+		Expect(err).ToNot(HaveOccurred())
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		Expect(lines).To(HaveLen(7))
+		Expect(lines[0]).To(Equal("x0,x1,label"))
+
+		path := filepath.Join(dir, "data", "xor.csv")
+		_, _, err = run("", "dataset", "-dataset", "xor", "-samples", "10", "-o", path)
+		Expect(err).ToNot(HaveOccurred())
+		b, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Count(string(b), "\n")).To(Equal(11))
+	})
+
+	It("Trains, evaluates and predicts", func() {
+		dataPath := filepath.Join(dir, "moons.csv")
+		model := filepath.Join(dir, "models", "moons.json")
+
+		_, _, err := run("", "dataset", "-samples", "60", "-o", dataPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		stdout, _, err := run("", "train",
+			"-data", dataPath,
+			"-hidden", "8",
+			"-act", "tanh",
+			"-optimizer", "adam",
+			"-lr", "0.05",
+			"-epochs", "30",
+			"-o", model,
+			"-history", filepath.Join(dir, "plots", "history.png"),
+			"-boundary", filepath.Join(dir, "plots", "boundary.png"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Step 0 loss"))
+		Expect(stdout).To(ContainSubstring("Step 29 loss"))
+		Expect(stdout).To(ContainSubstring("validation loss"))
+		Expect(stdout).To(ContainSubstring("Saved " + model))
+		Expect(filepath.Join(dir, "plots", "history.png")).To(BeAnExistingFile())
+		Expect(filepath.Join(dir, "plots", "boundary.png")).To(BeAnExistingFile())
+
+		stdout, _, err = run("", "eval", "-model", model, "-data", dataPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout).To(MatchRegexp(`^60 samples, loss \d+\.\d+, accuracy \d+\.\d%\n$`))
+
+		stdout, _, err = run("", "predict", "-model", model, "1,0.5", "-1,0.5")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout).To(MatchRegexp(`^[01]\n[01]\n$`))
+
+		stdout, _, err = run("x0,x1,label\n1,0.5,0\n0,0,1\n-1,0.5,0\n", "predict", "-model", model, "-target", "label")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Fields(stdout)).To(HaveLen(3))
+
+		_, _, err = run("", "predict", "-model", model, "1,2,3")
+		Expect(err).To(HaveOccurred())
+
+		graph := filepath.Join(dir, "graph", "mlp.gv")
+		_, _, err = run("", "graph", "-model", model, "-input", "1,0.5", "-collapse", "neuron", "-o", graph)
+		Expect(err).ToNot(HaveOccurred())
+		b, err := os.ReadFile(graph)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring("layers.0.neurons.7"))
+	})
+
+	It("Reports missing checkpoints", func() {
+		_, _, err := run("", "eval", "-model", filepath.Join(dir, "missing.json"))
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(MatchError(cli.ErrUsage))
+	})
+})
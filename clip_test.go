@@ -0,0 +1,55 @@
+package main_test
+
+import (
+	"math"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Clipping", func() {
+	var gc *grad.Context[float64]
+	var ps []*grad.Value[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		ps = gc.Vals(3, -4)
+		// Gradients of sum(p^2) are 2p, i.e. (6, -8)
+		gc.Backward(gc.SumSq(ps))
+	})
+
+	It("Clips by global norm", func() {
+		norm := grad.ClipGradNorm(slices.Values(ps), 5)
+
+		Expect(norm).To(BeNumerically("~", 10))
+		Expect(ps[0].Grad()).To(BeNumerically("~", 3))
+		Expect(ps[1].Grad()).To(BeNumerically("~", -4))
+		Expect(grad.GradNorm(slices.Values(ps))).To(BeNumerically("~", 5))
+	})
+
+	It("Leaves small gradients alone", func() {
+		norm := grad.ClipGradNorm(slices.Values(ps), 100)
+
+		Expect(norm).To(BeNumerically("~", 10))
+		Expect(ps[0].Grad()).To(BeNumerically("~", 6))
+	})
+
+	It("Clips by value", func() {
+		norm := grad.ClipGradValue(slices.Values(ps), 7)
+
+		Expect(norm).To(BeNumerically("~", 10))
+		Expect(ps[0].Grad()).To(BeNumerically("~", 6))
+		Expect(ps[1].Grad()).To(BeNumerically("~", -7))
+	})
+
+	It("Does not spread NaN", func() {
+		nan := gc.Val(math.NaN())
+		gc.Backward(gc.SumSq([]*grad.Value[float64]{ps[0], nan}))
+
+		norm := grad.ClipGradNorm(slices.Values([]*grad.Value[float64]{ps[0], nan}), 1)
+		Expect(math.IsNaN(norm)).To(BeTrue())
+		Expect(ps[0].Grad()).To(BeNumerically("~", 6))
+	})
+})
@@ -0,0 +1,163 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/cli"
+	"github.com/richiejp/micrograd/internal/config"
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Experiment config", func() {
+	It("Fills in defaults and keeps explicit values", func() {
+		e := must(config.Read(strings.NewReader(`
+dataset:
+  name: circles
+  val: 0
+model:
+  hidden: [4]
+  activation: tanh
+optimizer:
+  name: adam
+  learning_rate: 0.05
+`), config.YAML))
+
+		Expect(e.Dataset.Name).To(Equal("circles"))
+		Expect(e.Dataset.Val).To(BeZero())
+		Expect(e.Dataset.Samples).To(Equal(config.Default().Dataset.Samples))
+		Expect(e.Model.Hidden).To(Equal([]uint{4}))
+		Expect(e.Model.Activation).To(Equal(grad.TanhActFn))
+		Expect(e.Optimizer.Name).To(Equal(grad.AdamOptim))
+		Expect(e.Schedule.Epochs).To(Equal(100))
+
+		e = must(config.Read(strings.NewReader(`{"schedule": {"epochs": 3}}`), config.JSON))
+		Expect(e.Schedule.Epochs).To(Equal(3))
+		Expect(e.Model.Hidden).To(Equal(config.Default().Model.Hidden))
+
+		e = must(config.Read(strings.NewReader(""), config.YAML))
+		Expect(*e).To(Equal(config.Default()))
+	})
+
+	It("Rejects unknown fields and invalid settings", func() {
+		_, err := config.Read(strings.NewReader("optimizer:\n  learning_rat: 0.1\n"), config.YAML)
+		Expect(err).To(MatchError(ContainSubstring("learning_rat")))
+
+		_, err = config.Read(strings.NewReader(`{"model": {"layers": [2]}}`), config.JSON)
+		Expect(err).To(MatchError(ContainSubstring("layers")))
+
+		_, err = config.Read(strings.NewReader(`
+dataset:
+  name: iris
+model:
+  hidden: [8, 0]
+optimizer:
+  name: lbfgs
+schedule:
+  epochs: 0
+`), config.YAML)
+		Expect(err).To(HaveOccurred())
+		for _, field := range []string{"dataset.name", "model.hidden[1]", "optimizer.name", "schedule.epochs"} {
+			Expect(err.Error()).To(ContainSubstring(field + ": "))
+		}
+
+		e := config.Default()
+		e.Dataset.Path = "data.csv"
+		e.Dataset.Name = ""
+		e.Dataset.Target = ""
+		Expect(e.Validate()).To(MatchError(ContainSubstring("dataset.target")))
+	})
+
+	It("Round trips through YAML and JSON", func() {
+		e := config.Default()
+		e.Name = "round trip"
+		e.Model.Hidden = []uint{3, 5}
+		e.Optimizer.LearningRate = 0.25
+
+		for _, format := range []config.Format{config.YAML, config.JSON} {
+			var b strings.Builder
+			Expect(e.Write(&b, format)).To(Succeed())
+			Expect(*must(config.Read(strings.NewReader(b.String()), format))).To(Equal(e), string(format))
+		}
+
+		Expect(config.FormatOf("a/b.JSON")).To(Equal(config.JSON))
+		Expect(config.FormatOf("a/b.yml")).To(Equal(config.YAML))
+	})
+
+	It("Validates the example experiments", func() {
+		paths, err := filepath.Glob("experiments/*.yaml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).ToNot(BeEmpty())
+
+		for _, path := range paths {
+			_, err := config.Load(path)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("Loads named labels from a file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "pets.csv")
+		Expect(os.WriteFile(path, []byte("weight,species\n4,cat\n30,dog\n5,cat\n"), 0o644)).To(Succeed())
+
+		e := must(config.Read(strings.NewReader(`
+dataset:
+  path: `+path+`
+  target: species
+  categorical_target: true
+`), config.YAML))
+
+		X, y, err := e.Dataset.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(X).To(Equal([][]float64{{4}, {30}, {5}}))
+		Expect(y).To(Equal([]int{0, 1, 0}))
+
+		e.Dataset.CategoricalTarget = false
+		_, _, err = e.Dataset.Load()
+		Expect(err).To(MatchError(ContainSubstring(`value "cat"`)))
+	})
+
+	It("Trains from a file with flags overriding it", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "exp.yaml")
+		saved := filepath.Join(dir, "saved.json")
+		model := filepath.Join(dir, "model.json")
+
+		Expect(os.WriteFile(path, []byte(`
+dataset:
+  samples: 40
+model:
+  hidden: [4]
+schedule:
+  epochs: 50
+logging:
+  every: 0
+  checkpoint: `+model+`
+`), 0o644)).To(Succeed())
+
+		var stdout, stderr strings.Builder
+		err := cli.Run([]string{"train", "-config", path, "-epochs", "5", "-save-config", saved},
+			nil, &stdout, &stderr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout.String()).To(ContainSubstring("Step 4 loss"))
+		Expect(stdout.String()).ToNot(ContainSubstring("Step 0 loss"))
+
+		e := must(config.Load(saved))
+		Expect(e.Schedule.Epochs).To(Equal(5))
+		Expect(e.Dataset.Samples).To(Equal(40))
+		Expect(e.Model.Hidden).To(Equal([]uint{4}))
+
+		stdout.Reset()
+		err = cli.Run([]string{"eval", "-config", saved}, nil, &stdout, &stderr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout.String()).To(HavePrefix("40 samples"))
+
+		stderr.Reset()
+		err = cli.Run([]string{"train", "-config", filepath.Join(dir, "missing.yaml")}, nil, &stdout, &stderr)
+		Expect(err).To(MatchError(cli.ErrUsage))
+		Expect(stderr.String()).To(ContainSubstring("missing.yaml"))
+	})
+})
@@ -0,0 +1,88 @@
+package main_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/data"
+)
+
+var _ = Describe("CSV", func() {
+	const iris = `sepal,petal,colour,species
+5.1,1.4,red,setosa
+7.0,4.7,blue,versicolor
+6.3,NA,red,virginica
+4.9,1.4,green,setosa
+`
+
+	It("Loads features and a categorical target", func() {
+		ds, err := data.ReadCSV(strings.NewReader(iris), data.CSVOptions{
+			Header:            true,
+			Features:          []string{"sepal", "colour"},
+			Target:            "species",
+			CategoricalTarget: true,
+			Categorical:       []string{"colour"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ds.Features).To(Equal([]string{"sepal", "colour"}))
+		Expect(ds.Classes).To(Equal([]string{"setosa", "versicolor", "virginica"}))
+		Expect(ds.Labels).To(Equal([]int{0, 1, 2, 0}))
+		Expect(ds.Categories["colour"]).To(Equal([]string{"blue", "green", "red"}))
+		Expect(ds.X[1]).To(Equal([]float64{7.0, 0}))
+		Expect(ds.Y).To(BeNil())
+	})
+
+	It("Applies missing value policies", func() {
+		opts := data.CSVOptions{
+			Header:            true,
+			Features:          []string{"sepal", "petal"},
+			Target:            "species",
+			CategoricalTarget: true,
+		}
+
+		_, err := data.ReadCSV(strings.NewReader(iris), opts)
+		Expect(err).To(MatchError(data.ErrMissingValue))
+		var perr *data.ParseError
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Row).To(Equal(4))
+		Expect(perr.Column).To(Equal("petal"))
+
+		opts.Missing = data.MissingSkipRow
+		ds, err := data.ReadCSV(strings.NewReader(iris), opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ds.X).To(HaveLen(3))
+		Expect(ds.Labels).To(Equal([]int{0, 1, 0}))
+
+		opts.Missing = data.MissingMean
+		ds, err = data.ReadCSV(strings.NewReader(iris), opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ds.X[2][1]).To(BeNumerically("~", (1.4+4.7+1.4)/3))
+	})
+
+	It("Reports type errors by row and column", func() {
+		_, err := data.ReadCSV(strings.NewReader("1,2\n3,x\n"), data.CSVOptions{Target: "1"})
+
+		var perr *data.ParseError
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Row).To(Equal(2))
+		Expect(perr.Column).To(Equal("1"))
+		Expect(errors.Is(err, strconv.ErrSyntax)).To(BeTrue())
+	})
+
+	It("Loads TSV files", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "data.tsv")
+		Expect(os.WriteFile(path, []byte("a\tb\ty\n1\t2\t0.5\n3\t4\t1.5\n"), 0o644)).To(Succeed())
+
+		ds, err := data.LoadCSV(path, data.CSVOptions{Header: true, Target: "y"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ds.X).To(Equal([][]float64{{1, 2}, {3, 4}}))
+		Expect(ds.Y).To(Equal([]float64{0.5, 1.5}))
+	})
+})
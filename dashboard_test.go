@@ -0,0 +1,146 @@
+package main_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/dashboard"
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/train"
+	"github.com/richiejp/micrograd/internal/viz"
+)
+
+var _ = Describe("Dashboard", func() {
+	var dash *dashboard.Dashboard
+	var srv *httptest.Server
+
+	BeforeEach(func() {
+		dash = dashboard.New("Test run")
+		srv = httptest.NewServer(dash.Handler())
+		DeferCleanup(srv.Close)
+	})
+
+	get := func(path string) (*http.Response, string) {
+		res, err := http.Get(srv.URL + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		return res, string(b)
+	}
+
+	It("Serves a page without external dependencies", func() {
+		res, body := get("/")
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("Content-Type")).To(HavePrefix("text/html"))
+		Expect(body).To(ContainSubstring("<canvas"))
+		Expect(body).To(ContainSubstring("EventSource"))
+		Expect(body).ToNot(MatchRegexp(`(src|href)="?https?:`))
+
+		res, _ = get("/missing")
+		Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("Records the steps of training", func() {
+		X, y := data.MakeMoonsSeeded(20, 0.1, true, 1)
+		_, err := train.Fit(X, y, train.Options{Hidden: []uint{4}, Steps: 3, Seed: 1, ValX: X, ValY: y, OnStep: dash.Record})
+		Expect(err).ToNot(HaveOccurred())
+
+		// Can't be encoded as JSON so is left out
+		dash.Record(train.Step{Step: 3, Train: train.Metrics{Loss: math.Inf(1)}})
+		Expect(dash.History().Steps).To(HaveLen(4))
+
+		res, body := get("/history")
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+		var h struct {
+			Title    string       `json:"title"`
+			Steps    []train.Step `json:"steps"`
+			Boundary int          `json:"boundary"`
+		}
+		Expect(json.Unmarshal([]byte(body), &h)).To(Succeed())
+		Expect(h.Title).To(Equal("Test run"))
+		Expect(h.Steps).To(HaveLen(3))
+		Expect(h.Steps[2].Step).To(Equal(2))
+		Expect(h.Steps[2].Val).ToNot(BeNil())
+		Expect(h.Steps[2].GradNorm).To(BeNumerically(">", 0))
+		Expect(h.Boundary).To(Equal(0))
+	})
+
+	It("Serves the decision boundary", func() {
+		res, _ := get("/boundary.png")
+		Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+
+		X, y := data.MakeMoonsSeeded(20, 0.1, true, 1)
+		c, err := train.Fit(X, y, train.Options{Hidden: []uint{4}, Steps: 1, Seed: 1})
+		Expect(err).ToNot(HaveOccurred())
+
+		var png bytes.Buffer
+		Expect(viz.WriteBoundary(&png, "png", c.Context(), c.Model(), X, y, viz.BoundaryOptions{Resolution: 10})).To(Succeed())
+		dash.SetBoundary(png.Bytes())
+
+		res, body := get("/boundary.png")
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("Content-Type")).To(Equal("image/png"))
+		Expect(body).To(HavePrefix("\x89PNG"))
+
+		_, body = get("/history")
+		Expect(body).To(ContainSubstring(`"boundary":1`))
+	})
+
+	It("Streams updates", func() {
+		res, err := http.Get(srv.URL + "/events")
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+		Expect(res.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		// The client is subscribed once the headers are sent
+		dash.Record(train.Step{Step: 7, Train: train.Metrics{Loss: 0.5, Accuracy: 0.75}})
+		dash.SetBoundary([]byte("\x89PNG"))
+
+		r := bufio.NewReader(res.Body)
+		readEvent := func() []string {
+			var lines []string
+			for {
+				line, err := r.ReadString('\n')
+				Expect(err).ToNot(HaveOccurred())
+				line = strings.TrimSuffix(line, "\n")
+				if line == "" {
+					return lines
+				}
+				lines = append(lines, line)
+			}
+		}
+
+		Expect(readEvent()).To(Equal([]string{
+			"event: step",
+			`data: {"step":7,"train":{"loss":0.5,"accuracy":0.75},"learning_rate":0,"grad_norm":0}`,
+		}))
+		Expect(readEvent()).To(Equal([]string{"event: boundary", "data: 1"}))
+	})
+
+	It("Listens on a free port", func() {
+		url, err := dash.Listen("localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(dash.Close)
+
+		_, err = dash.Listen("localhost:0")
+		Expect(err).To(HaveOccurred())
+
+		res, err := http.Get(url + "history")
+		Expect(err).ToNot(HaveOccurred())
+		res.Body.Close()
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+	})
+})
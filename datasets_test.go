@@ -0,0 +1,109 @@
+package main_test
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/data"
+)
+
+var _ = Describe("Datasets", func() {
+	counts := func(y []int) map[int]int {
+		c := make(map[int]int)
+		for _, yi := range y {
+			c[yi]++
+		}
+		return c
+	}
+
+	It("Generates reproducible moons", func() {
+		X, y := data.MakeMoonsSeeded(50, 0.1, true, 3)
+		X2, y2 := data.MakeMoonsSeeded(50, 0.1, true, 3)
+
+		Expect(X).To(Equal(X2))
+		Expect(y).To(Equal(y2))
+		Expect(counts(y)).To(Equal(map[int]int{0: 25, 1: 25}))
+	})
+
+	It("Generates circles", func() {
+		X, y := data.MakeCircles(40, 0, 0.5, 1)
+
+		Expect(X).To(HaveLen(40))
+		for i, x := range X {
+			r := x[0]*x[0] + x[1]*x[1]
+			if y[i] == 0 {
+				Expect(r).To(BeNumerically("~", 1))
+			} else {
+				Expect(r).To(BeNumerically("~", 0.25))
+			}
+		}
+	})
+
+	It("Generates spirals", func() {
+		X, y, err := data.MakeSpirals(100, 3, 0.2, 1)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(X).To(HaveLen(100))
+		Expect(counts(y)).To(Equal(map[int]int{0: 34, 1: 33, 2: 33}))
+
+		_, _, err = data.MakeSpirals(100, 0, 0.2, 1)
+		Expect(err).To(MatchError(ContainSubstring("at least one class")))
+	})
+
+	It("Generates XOR", func() {
+		X, y := data.MakeXOR(60, 0, 1)
+
+		for i, x := range X {
+			Expect(y[i] == 1).To(Equal((x[0] > 0) != (x[1] > 0)))
+		}
+	})
+
+	It("Generates classification problems", func() {
+		X, y, err := data.MakeClassification(90, 6, 2, 2, 3, 2, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(X).To(HaveLen(90))
+		Expect(X[0]).To(HaveLen(6))
+		Expect(counts(y)).To(Equal(map[int]int{0: 30, 1: 30, 2: 30}))
+
+		_, _, err = data.MakeClassification(10, 3, 2, 2, 2, 1, 1)
+		Expect(err).To(HaveOccurred())
+		_, _, err = data.MakeClassification(10, 3, 1, 0, 3, 1, 1)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Generates datasets by name", func() {
+		X, y, err := data.Generate("moons", data.GenerateOptions{Samples: 20, Noise: -1, Seed: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(X).To(HaveLen(20))
+		Expect(counts(y)).To(HaveLen(2))
+
+		X, _, err = data.Generate("blobs", data.GenerateOptions{Samples: 20, Noise: -1, Classes: 4, Seed: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(X).To(HaveLen(20))
+
+		for _, name := range []string{"blobs", "spirals", "classification"} {
+			_, _, err = data.Generate(name, data.GenerateOptions{Samples: 20, Seed: 1})
+			Expect(err).To(MatchError(ContainSubstring("two classes")), name)
+		}
+
+		_, _, err = data.MakeBlobs(20, nil, 1, 1)
+		Expect(err).To(MatchError(ContainSubstring("at least one centre")))
+		_, _, err = data.MakeBlobs(20, [][]float64{{0, 0}, {1}}, 1, 1)
+		Expect(err).To(MatchError(ContainSubstring("centre 1 has 1 dimensions")))
+	})
+
+	It("Generates regression problems", func() {
+		X, y := data.MakeRegression(20, 3, 0, 1)
+		X2, y2 := data.MakeRegression(20, 3, 0, 1)
+
+		Expect(X).To(Equal(X2))
+		Expect(y).To(Equal(y2))
+		Expect(X[0]).To(HaveLen(3))
+
+		R, t := data.MakeSwissRoll(20, 0, 1)
+		Expect(R[0]).To(HaveLen(3))
+		Expect(R[0][0]).To(BeNumerically("~", t[0]*math.Cos(t[0])))
+	})
+})
@@ -0,0 +1,112 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Dropout", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		gc.Seed(1)
+	})
+
+	It("Only passes gradient through kept units", func() {
+		d, err := gc.Dropout(0.5)
+		Expect(err).ToNot(HaveOccurred())
+
+		xs := gc.Vals(1, 2, 3, 4, 5, 6, 7, 8)
+		outs := d.Forward(xs)
+		gc.Backward(gc.Sum(outs))
+
+		kept := 0
+		for i, o := range outs {
+			if o.Data() == 0 {
+				Expect(xs[i].Grad()).To(Equal(0.0))
+			} else {
+				kept++
+				Expect(o.Data()).To(Equal(2 * xs[i].Data()))
+				Expect(xs[i].Grad()).To(Equal(2.0))
+			}
+		}
+		Expect(kept).To(BeNumerically(">", 0))
+		Expect(kept).To(BeNumerically("<", len(xs)))
+	})
+
+	It("Is the identity in evaluation mode", func() {
+		d, _ := gc.Dropout(0.9)
+		d.SetTraining(false)
+
+		xs := gc.Vals(1, 2, 3)
+		Expect(d.Forward(xs)).To(Equal(xs))
+	})
+
+	It("Is reproducible with a seeded context", func() {
+		xs := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+		run := func() ([]float64, []float64) {
+			gc := &grad.Context[float64]{}
+			gc.Seed(42)
+
+			d := must(gc.Dropout(0.25))
+			var dropped []float64
+			for _, o := range d.Forward(gc.Vals(xs...)) {
+				dropped = append(dropped, o.Data())
+			}
+
+			in := gc.Vals(xs...)
+			d.SetTraining(false)
+			Expect(d.Forward(in)).To(Equal(in))
+
+			n, err := gc.MLPWith([]uint{2, 8, 1}, gc.WithDropout(0.5))
+			Expect(err).ToNot(HaveOccurred())
+
+			return dropped, []float64{
+				n.Forward(gc.Vals(1, 1))[0].Data(),
+				n.Forward(gc.Vals(1, 1))[0].Data(),
+			}
+		}
+
+		dropped, outs := run()
+
+		// Some units are zeroed and the rest are scaled by 1/(1-p)
+		zeroed := 0
+		for i, v := range dropped {
+			if v == 0 {
+				zeroed++
+			} else {
+				Expect(v).To(BeNumerically("~", xs[i]/0.75))
+			}
+		}
+		Expect(zeroed).To(BeNumerically(">", 0))
+		Expect(zeroed).To(BeNumerically("<", len(xs)))
+
+		// Each forward pass of the MLP draws a new mask
+		Expect(outs[0]).ToNot(Equal(outs[1]))
+
+		dropped2, outs2 := run()
+		Expect(dropped2).To(Equal(dropped))
+		Expect(outs2).To(Equal(outs))
+	})
+
+	It("Can be switched off in an MLP", func() {
+		n, err := gc.MLPWith([]uint{2, 8, 1}, gc.WithDropout(0.5))
+		Expect(err).ToNot(HaveOccurred())
+
+		n.SetTraining(false)
+		a := n.Forward(gc.Vals(1, 1))[0].Data()
+		b := n.Forward(gc.Vals(1, 1))[0].Data()
+		Expect(a).To(Equal(b))
+	})
+
+	It("Rejects invalid probabilities", func() {
+		_, err := gc.Dropout(1)
+		Expect(err).To(HaveOccurred())
+
+		_, err = gc.MLPWith([]uint{2, 1}, gc.WithDropout(-0.1))
+		Expect(err).To(HaveOccurred())
+	})
+})
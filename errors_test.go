@@ -0,0 +1,90 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Errors", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+	})
+
+	It("Reports input size mismatches", func() {
+		n := gc.MLP(3, 2, 1)
+
+		_, err := n.ForwardE(gc.Vals(1, 2))
+		Expect(err).To(MatchError(grad.ErrInputSize))
+		Expect(err).To(MatchError(ContainSubstring("layer 0: neuron 0")))
+
+		_, err = gc.Seq(gc.Lay(2, 3), gc.LayerNorm(2)).ForwardE(gc.Vals(1, 2))
+		Expect(err).To(MatchError(grad.ErrInputSize))
+
+		_, err = grad.Softmax[float64]{}.ForwardE(nil)
+		Expect(err).To(MatchError(grad.ErrInputSize))
+
+		Expect(func() { n.Forward(gc.Vals(1, 2, 3, 4)) }).To(PanicWith(MatchError(grad.ErrInputSize)))
+	})
+
+	It("Reports bad logits and labels", func() {
+		_, err := gc.SoftmaxE(nil)
+		Expect(err).To(MatchError(grad.ErrInputSize))
+
+		_, err = gc.CrossEntropyE(nil, 0)
+		Expect(err).To(MatchError(grad.ErrInputSize))
+
+		for _, label := range []int{-1, 3} {
+			_, err = gc.CrossEntropyE(gc.Vals(1, 2, 3), label)
+			Expect(err).To(MatchError(grad.ErrBadLabel))
+		}
+
+		Expect(func() { gc.CrossEntropy(gc.Vals(1, 2), 2) }).To(PanicWith(MatchError(grad.ErrBadLabel)))
+	})
+
+	It("Reports empty sums", func() {
+		_, err := gc.SumE(nil)
+		Expect(err).To(MatchError(grad.ErrEmptySum))
+	})
+
+	It("Reports unknown ops and malformed values", func() {
+		a := gc.Val(1)
+		b := gc.Val(2, gc.WithPrev(a))
+		Expect(gc.Backward(b)).To(MatchError(grad.ErrMalformedValue))
+
+		c := gc.Val(3, gc.WithPrev(a), gc.WithOp("sigmoid"))
+		Expect(gc.Backward(c)).To(MatchError(grad.ErrUnknownOp))
+
+		d := gc.Val(4, gc.WithPrev(a), gc.WithOp(grad.OpMul))
+		Expect(gc.Backward(d)).To(MatchError(grad.ErrMalformedValue))
+	})
+
+	It("Leaves the gradients alone when the graph is malformed", func() {
+		a := gc.Val(2)
+		Expect(gc.Backward(a.Mul(gc.Val(3)))).To(Succeed())
+		Expect(a.Grad()).To(Equal(3.0))
+
+		// The gradients of the last successful pass are kept
+		bad := gc.Val(1, gc.WithPrev(a), gc.WithOp(grad.OpMul))
+		Expect(gc.Backward(a.Mul(gc.Val(5)).Add(bad))).To(MatchError(grad.ErrMalformedValue))
+		Expect(a.Grad()).To(Equal(3.0))
+	})
+
+	It("Fails to check gradients of a malformed graph", func() {
+		a := gc.Val(2)
+		bad := gc.Val(1, gc.WithPrev(a), gc.WithOp(grad.OpMul))
+
+		_, err := gc.CheckGrad([]*grad.Value[float64]{a}, func() *grad.Value[float64] {
+			return a.Mul(a).Add(bad)
+		}, 1e-6)
+		Expect(err).To(MatchError(grad.ErrMalformedValue))
+	})
+
+	It("Works with modules which are not checked", func() {
+		out, err := grad.ForwardE[float64](double{}, gc.Vals(1, 2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out[1].Data()).To(Equal(4.0))
+	})
+})
@@ -0,0 +1,92 @@
+package main_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/train"
+	"github.com/richiejp/micrograd/internal/viz"
+)
+
+var _ = Describe("History", func() {
+	X, y := data.MakeMoonsSeeded(40, 0.1, true, 1)
+	trainIdx, valIdx, _ := data.TrainTestSplit(y, 0.25, true, 1)
+	trainX, trainY := data.Take(X, y, trainIdx)
+	valX, valY := data.Take(X, y, valIdx)
+
+	It("Records each step", func() {
+		var h train.History
+		opts := train.Options{
+			Hidden:            []uint{4},
+			Steps:             5,
+			LearningRate:      0.2,
+			FinalLearningRate: 0.1,
+			Seed:              1,
+			History:           &h,
+			ValX:              valX,
+			ValY:              valY,
+		}
+
+		_, err := train.Fit(trainX, trainY, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(h.Steps).To(HaveLen(5))
+		Expect(h.HasVal()).To(BeTrue())
+		Expect(h.Steps[0].LearningRate).To(BeNumerically("~", 0.2))
+		Expect(h.Steps[4].LearningRate).To(BeNumerically("~", 0.1))
+
+		for i, s := range h.Steps {
+			Expect(s.Step).To(Equal(i))
+			Expect(s.Train.Accuracy).To(BeNumerically(">=", 0))
+			Expect(s.Train.Accuracy).To(BeNumerically("<=", 1))
+			Expect(s.Val).ToNot(BeNil())
+			Expect(s.GradNorm).To(BeNumerically(">", 0))
+		}
+
+		last, ok := h.Last()
+		Expect(ok).To(BeTrue())
+		Expect(last.Step).To(Equal(4))
+
+		b, err := json.Marshal(h)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"learning_rate":0.2`))
+	})
+
+	It("Matches the metrics of Evaluate", func() {
+		var h train.History
+		opts := train.Options{Hidden: []uint{4}, Steps: 1, Seed: 1, History: &h, ValX: valX, ValY: valY}
+
+		c, err := train.New(2, 2, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		before, err := c.Evaluate(valX, valY)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(c.Train(trainX, trainY, opts)).To(Succeed())
+		Expect(*h.Steps[0].Val).To(Equal(before))
+	})
+
+	It("Plots the history", func() {
+		var h train.History
+		_, err := train.Fit(trainX, trainY, train.Options{Hidden: []uint{4}, Steps: 3, Seed: 1, History: &h})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(h.HasVal()).To(BeFalse())
+
+		dir := GinkgoT().TempDir()
+		for _, name := range []string{"history.png", "history.svg"} {
+			path := filepath.Join(dir, name)
+			Expect(viz.PlotHistory(path, &h)).To(Succeed())
+
+			info, err := os.Stat(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size()).To(BeNumerically(">", 0))
+		}
+
+		Expect(viz.PlotHistory(filepath.Join(dir, "history.png"), &train.History{})).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Name of the program in usage messages
+const Name = "micrograd"
+
+// ErrUsage is returned for bad arguments, a message and the usage have
+// already been written to Stderr
+var ErrUsage = errors.New("usage error")
+
+// Env is where a command reads its input and writes its output
+type Env struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Command is a subcommand, Run is given the arguments after its name
+type Command struct {
+	Name string
+	// Summary is one line shown in the list of commands
+	Summary string
+	Run     func(env *Env, args []string) error
+}
+
+// Commands are those built into the CLI
+var Commands = []Command{
+	{Name: "train", Summary: "train a classifier and save a checkpoint", Run: runTrain},
+	{Name: "eval", Summary: "measure the loss and accuracy of a checkpoint on a dataset", Run: runEval},
+	{Name: "predict", Summary: "print the class of each sample", Run: runPredict},
+	{Name: "graph", Summary: "draw the computation graph of a checkpoint", Run: runGraph},
+	{Name: "search", Summary: "rank configurations from a space of hyperparameters", Run: runSearch},
+	{Name: "dataset", Summary: "generate a toy dataset as CSV", Run: runDataset},
+}
+
+func usage(w io.Writer, cmds []Command) {
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n\ncommands:\n", Name)
+	for _, c := range cmds {
+		fmt.Fprintf(w, "  %-8s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command\n", Name)
+}
+
+// Run executes the command named by args[0], extra commands can be added
+// to the built in ones
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer, extra ...Command) error {
+	env := &Env{Stdin: stdin, Stdout: stdout, Stderr: stderr}
+	cmds := append(slices.Clone(Commands), extra...)
+
+	if len(args) < 1 {
+		usage(stderr, cmds)
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		usage(stdout, cmds)
+		return nil
+	}
+
+	for _, c := range cmds {
+		if c.Name != args[0] {
+			continue
+		}
+
+		// Asking for help is not an error
+		if err := c.Run(env, args[1:]); !errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return nil
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+	usage(stderr, cmds)
+
+	return ErrUsage
+}
+
+// Flags returns a flag set for the command name, synopsis describes
+// the arguments which follow the flags, if any
+func (e *Env) Flags(name string, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.Stderr)
+	fs.Usage = func() {
+		line := strings.TrimSpace(fmt.Sprintf("usage: %s %s [flags] %s", Name, name, synopsis))
+		fmt.Fprintf(e.Stderr, "%s\n\nflags:\n", line)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// Parse parses args with fs, if help was asked for it returns
+// flag.ErrHelp which the command should return to stop
+func (e *Env) Parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return ErrUsage
+	}
+
+	return nil
+}
+
+// usagef reports a bad argument like the flag package does
+func (e *Env) usagef(fs *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(e.Stderr, format+"\n", args...)
+	fs.Usage()
+
+	return ErrUsage
+}
+
+// create creates path along with any missing directories
+func create(path string) (*os.File, error) {
+	if err := mkdirFor(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("Create %s: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// mkdirFor creates the directory which will contain path
+func mkdirFor(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("MkdirAll %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// parseFloats parses a comma separated list, e.g. "0.5,-1"
+func parseFloats(s string) ([]float64, error) {
+	var xs []float64
+	for _, f := range strings.Split(s, ",") {
+		x, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+
+	return xs, nil
+}
+
+// uintList is a flag holding a comma separated list such as "16,16"
+type uintList []uint
+
+func (l *uintList) String() string {
+	var parts []string
+	for _, n := range *l {
+		parts = append(parts, strconv.FormatUint(uint64(n), 10))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (l *uintList) Set(s string) error {
+	*l = nil
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(f), 10, 32)
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return errors.New("sizes must be at least 1")
+		}
+		*l = append(*l, uint(n))
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/richiejp/micrograd/internal/config"
+	"github.com/richiejp/micrograd/internal/data"
+)
+
+// registerGenerator adds flags choosing and configuring a generated
+// dataset, seedFlag is the name of the seed flag which differs between
+// commands
+func registerGenerator(fs *flag.FlagSet, d *config.Dataset, seedFlag string) {
+	fs.StringVar(&d.Name, "dataset", d.Name, "generated dataset, one of "+strings.Join(data.GeneratorNames(), ", "))
+	fs.IntVar(&d.Samples, "samples", d.Samples, "number of generated samples")
+	fs.Float64Var(&d.Noise, "noise", d.Noise, "noise of the generated dataset, or the class separation of classification, negative for the dataset's default")
+	fs.IntVar(&d.Classes, "classes", d.Classes, "number of classes for blobs, spirals and classification")
+	fs.IntVar(&d.Features, "features", d.Features, "number of features for classification")
+	fs.Int64Var(&d.Seed, seedFlag, d.Seed, "seed for generating the dataset and splitting it")
+}
+
+// registerDataset adds flags to load a dataset from a file or generate
+// one
+func registerDataset(fs *flag.FlagSet, d *config.Dataset) {
+	fs.StringVar(&d.Path, "data", d.Path, "CSV or TSV file with a header row, if not set a dataset is generated")
+	fs.StringVar(&d.Target, "target", d.Target, "column of -data holding the class labels, which must be integers from 0 unless -categorical-target is set")
+	fs.BoolVar(&d.CategoricalTarget, "categorical-target", d.CategoricalTarget, "map the -target labels, such as names, to classes in sorted order")
+	registerGenerator(fs, d, "data-seed")
+}
+
+func datasetName(d config.Dataset) string {
+	if d.Path != "" {
+		return d.Path
+	}
+
+	return d.Name
+}
+
+// writeCSV writes the features as columns x0, x1... followed by label
+func writeCSV(w io.Writer, X [][]float64, y []int) error {
+	cw := csv.NewWriter(w)
+
+	var header []string
+	if len(X) > 0 {
+		for j := range X[0] {
+			header = append(header, "x"+strconv.Itoa(j))
+		}
+	}
+	header = append(header, "label")
+	cw.Write(header)
+
+	for i, x := range X {
+		rec := make([]string, 0, len(x)+1)
+		for _, v := range x {
+			rec = append(rec, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		rec = append(rec, strconv.Itoa(y[i]))
+		cw.Write(rec)
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func runDataset(env *Env, args []string) error {
+	fs := env.Flags("dataset", "")
+	d := config.Default().Dataset
+	registerGenerator(fs, &d, "seed")
+	out := fs.String("o", "-", "output file, - for stdout")
+
+	if err := env.Parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return env.usagef(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	X, y, err := d.Load()
+	if err != nil {
+		return env.usagef(fs, "%v", err)
+	}
+
+	if *out == "-" {
+		return writeCSV(env.Stdout, X, y)
+	}
+
+	file, err := create(*out)
+	if err != nil {
+		return err
+	}
+
+	if err := writeCSV(file, X, y); err != nil {
+		file.Close()
+		return fmt.Errorf("Write %s: %w", *out, err)
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/richiejp/micrograd/internal/grad"
+	"github.com/richiejp/micrograd/internal/train"
+	"github.com/richiejp/micrograd/internal/viz"
+)
+
+var directions = map[string]viz.Direction{
+	"TB": viz.TopToBottom,
+	"LR": viz.LeftToRight,
+	"BT": viz.BottomToTop,
+	"RL": viz.RightToLeft,
+}
+
+var clusterings = map[string]viz.Clustering{
+	"none":   viz.ClusterNone,
+	"layer":  viz.ClusterLayer,
+	"neuron": viz.ClusterNeuron,
+}
+
+func runGraph(env *Env, args []string) error {
+	fs := env.Flags("graph", "")
+	model := fs.String("model", "out/model.json", "checkpoint file")
+	input := fs.String("input", "", "comma separated features of the sample fed to the model, default all zeros")
+	out := fs.String("o", "out/graph.svg", "output file, the format is chosen by the extension: .gv or .dot for Graphviz, .html for an interactive page or an image such as .svg or .png")
+	direction := fs.String("direction", "LR", "direction of the graph, one of TB, LR, BT or RL")
+	cluster := fs.String("cluster", "layer", "group nodes by none, layer or neuron")
+	collapse := fs.String("collapse", "none", "replace each none, layer or neuron with a single node")
+	depth := fs.Int("depth", 0, "if above 0, only show nodes this many steps from the output")
+	colors := fs.Bool("colors", true, "colour ops, parameters and gradients")
+
+	if err := env.Parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return env.usagef(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	dir, ok := directions[strings.ToUpper(*direction)]
+	if !ok {
+		return env.usagef(fs, "unknown direction %q", *direction)
+	}
+	clusterBy, ok := clusterings[*cluster]
+	if !ok {
+		return env.usagef(fs, "unknown clustering %q", *cluster)
+	}
+	collapseBy, ok := clusterings[*collapse]
+	if !ok {
+		return env.usagef(fs, "unknown collapse %q", *collapse)
+	}
+
+	c, err := train.LoadFile(*model)
+	if err != nil {
+		return err
+	}
+
+	x := make([]float64, c.Checkpoint().Inputs)
+	if *input != "" {
+		if x, err = parseFloats(*input); err != nil {
+			return env.usagef(fs, "input: %v", err)
+		}
+	}
+
+	gc := c.Context()
+	outputs, err := grad.ForwardE(c.Module(), gc.Vals(x...))
+	if err != nil {
+		return fmt.Errorf("graph: %w", err)
+	}
+
+	// Show the gradients of the first output
+	if err := gc.Backward(outputs[0]); err != nil {
+		return fmt.Errorf("graph: %w", err)
+	}
+
+	opts := []viz.RenderOption{
+		viz.WithDirection(dir),
+		viz.WithModule(c.Model()),
+		viz.WithClusters(clusterBy),
+		viz.WithCollapse(collapseBy),
+		viz.WithMaxDepth(*depth),
+	}
+	if *colors {
+		opts = append(opts, viz.WithOpColors(), viz.WithParamHighlight(), viz.WithGradColors())
+	}
+
+	if err := mkdirFor(*out); err != nil {
+		return err
+	}
+
+	return viz.Render(*out, outputs[0], opts...)
+}
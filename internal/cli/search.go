@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/richiejp/micrograd/internal/config"
+	"github.com/richiejp/micrograd/internal/search"
+)
+
+func runSearch(env *Env, args []string) error {
+	fs := env.Flags("search", "")
+	e := config.Default()
+	registerExperiment(fs, &e)
+	configPath := fs.String("config", "", "YAML or JSON experiment file searched around, flags given with it override its settings")
+	spacePath := fs.String("space", "", "YAML or JSON file listing the params to search")
+	strategy := fs.String("strategy", string(search.Grid), "one of grid, random or halving")
+	trials := fs.Int("trials", 10, "number of configurations sampled by random and halving")
+	metric := fs.String("metric", string(search.Accuracy), "validation metric to rank by, accuracy or loss")
+	eta := fs.Int("eta", 3, "halving keeps the best 1/eta of the configurations each round")
+	parallel := fs.Int("parallel", 0, "number of trials run at once, 0 for the number of CPUs")
+	sampleSeed := fs.Int64("sample-seed", 1, "seed for sampling configurations")
+	out := fs.String("o", "out/search.csv", "results table ranking the trials")
+	best := fs.String("best", "", "if set, write the best experiment to this YAML or JSON file")
+	top := fs.Int("top", 10, "number of trials printed, 0 for all")
+
+	if err := env.parseExperiment(fs, &e, configPath, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return env.usagef(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	if *spacePath == "" {
+		return env.usagef(fs, "a -space file is needed")
+	}
+
+	space, err := search.LoadSpace(*spacePath)
+	if err != nil {
+		return env.usagef(fs, "%v", err)
+	}
+
+	opts := search.Options{
+		Strategy: search.Strategy(*strategy),
+		Trials:   *trials,
+		Metric:   search.Metric(*metric),
+		Eta:      *eta,
+		Parallel: *parallel,
+		Seed:     *sampleSeed,
+		OnTrial: func(t search.Trial) {
+			var params []string
+			for _, a := range t.Params {
+				params = append(params, a.String())
+			}
+
+			line := fmt.Sprintf("Trial %d round %d, %d epochs, %s: ", t.ID, t.Round, t.Experiment.Schedule.Epochs, strings.Join(params, " "))
+			if t.Err != nil {
+				line += t.Err.Error()
+			} else {
+				line += "validation " + formatMetrics(t.Val)
+			}
+			fmt.Fprintln(env.Stdout, line)
+		},
+	}
+
+	if err := opts.Validate(*space); err != nil {
+		return env.usagef(fs, "%v", err)
+	}
+
+	res, err := search.Run(e, *space, opts)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+
+	fmt.Fprintln(env.Stdout)
+	if err := res.WriteTable(env.Stdout, *top); err != nil {
+		return err
+	}
+
+	file, err := create(*out)
+	if err != nil {
+		return err
+	}
+	if err := res.WriteCSV(file); err != nil {
+		file.Close()
+		return fmt.Errorf("Write %s: %w", *out, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "Saved %s\n", *out)
+
+	t, ok := res.Best()
+	if !ok {
+		return fmt.Errorf("search: every trial failed")
+	}
+
+	if *best != "" {
+		if err := saveExperiment(*best, &t.Experiment); err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Stdout, "Saved %s\n", *best)
+	}
+
+	return nil
+}
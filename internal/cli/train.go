@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/richiejp/micrograd/internal/config"
+	"github.com/richiejp/micrograd/internal/dashboard"
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/train"
+	"github.com/richiejp/micrograd/internal/viz"
+)
+
+// registerExperiment adds flags for the dataset, model, loss, optimizer
+// and schedule of e, its values are the defaults
+func registerExperiment(fs *flag.FlagSet, e *config.Experiment) {
+	registerDataset(fs, &e.Dataset)
+	fs.Float64Var(&e.Dataset.Val, "val", e.Dataset.Val, "fraction of the data held out for validation, 0 for none")
+	fs.StringVar(&e.Dataset.Scale, "scale", e.Dataset.Scale, "feature scaling fitted on the training data, one of "+strings.Join(config.Scales, ", "))
+
+	fs.Var((*uintList)(&e.Model.Hidden), "hidden", "comma separated sizes of the hidden layers")
+	fs.StringVar((*string)(&e.Model.Activation), "act", string(e.Model.Activation), "activation of the hidden layers, one of relu, tanh or linear")
+	fs.Uint64Var(&e.Model.Seed, "seed", e.Model.Seed, "seed for the parameter initialisation")
+
+	fs.Float64Var(&e.Loss.L2, "l2", e.Loss.L2, "L2 regularization coefficient")
+
+	fs.StringVar((*string)(&e.Optimizer.Name), "optimizer", string(e.Optimizer.Name), "one of sgd, momentum or adam")
+	fs.Float64Var(&e.Optimizer.LearningRate, "lr", e.Optimizer.LearningRate, "learning rate, 0 for the optimizer's default")
+	fs.Float64Var(&e.Optimizer.ClipNorm, "clip", e.Optimizer.ClipNorm, "clip gradients to this global norm, 0 for no clipping")
+
+	fs.IntVar(&e.Schedule.Epochs, "epochs", e.Schedule.Epochs, "number of full batch gradient descent steps")
+	fs.Float64Var(&e.Schedule.FinalLearningRate, "final-lr", e.Schedule.FinalLearningRate, "if above 0 the learning rate decays linearly to this")
+}
+
+// registerLogging adds flags for the outputs of training
+func registerLogging(fs *flag.FlagSet, l *config.Logging) {
+	fs.StringVar(&l.Checkpoint, "o", l.Checkpoint, "checkpoint file")
+	fs.StringVar(&l.History, "history", l.History, "if set, plot the training history to this image")
+	fs.StringVar(&l.Boundary, "boundary", l.Boundary, "if set, plot the decision boundary of 2D data to this image")
+	fs.StringVar(&l.Dashboard, "dashboard", l.Dashboard, "if set, serve a live dashboard on this address, e.g. localhost:8080")
+	fs.IntVar(&l.Every, "log-every", l.Every, "print the metrics every this many steps, 0 for only the last")
+}
+
+// parseExperiment parses args into the flags registered on e. If the
+// experiment file flag is set then it replaces e and args are parsed
+// again, so that flags given on the command line override the file.
+func (env *Env) parseExperiment(fs *flag.FlagSet, e *config.Experiment, path *string, args []string) error {
+	if err := env.Parse(fs, args); err != nil {
+		return err
+	}
+
+	if *path != "" {
+		loaded, err := config.Load(*path)
+		if err != nil {
+			return env.usagef(fs, "%v", err)
+		}
+
+		*e = *loaded
+		if err := env.Parse(fs, args); err != nil {
+			return err
+		}
+	}
+
+	if err := e.Validate(); err != nil {
+		return env.usagef(fs, "%v", err)
+	}
+
+	return nil
+}
+
+func numClasses(y []int) int {
+	classes := 2
+	for _, yi := range y {
+		classes = max(classes, yi+1)
+	}
+
+	return classes
+}
+
+func formatMetrics(m train.Metrics) string {
+	return fmt.Sprintf("loss %.4f, accuracy %.1f%%", m.Loss, 100*m.Accuracy)
+}
+
+// saveExperiment writes e to path in the format chosen by its extension
+func saveExperiment(path string, e *config.Experiment) error {
+	file, err := create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := e.Write(file, config.FormatOf(path)); err != nil {
+		file.Close()
+		return fmt.Errorf("Write %s: %w", path, err)
+	}
+
+	return file.Close()
+}
+
+func runTrain(env *Env, args []string) error {
+	fs := env.Flags("train", "")
+	e := config.Default()
+	registerExperiment(fs, &e)
+	registerLogging(fs, &e.Logging)
+	configPath := fs.String("config", "", "YAML or JSON experiment file, flags given with it override its settings")
+	saveConfig := fs.String("save-config", "", "if set, write the experiment to this YAML or JSON file before training")
+
+	if err := env.parseExperiment(fs, &e, configPath, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return env.usagef(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	if *saveConfig != "" {
+		if err := saveExperiment(*saveConfig, &e); err != nil {
+			return err
+		}
+	}
+
+	opts := e.Options()
+	pre, err := e.Dataset.Preprocess()
+	if err != nil {
+		return env.usagef(fs, "%v", err)
+	}
+
+	X, y, err := e.Dataset.Load()
+	if err != nil {
+		return err
+	}
+	if len(X) < 1 {
+		return errors.New("train: no samples")
+	}
+
+	trainX, trainY, valX, valY, err := e.Dataset.Split(X, y)
+	if err != nil {
+		return err
+	}
+	opts.ValX, opts.ValY = valX, valY
+
+	if pre != nil {
+		if err := pre.Fit(trainX); err != nil {
+			return fmt.Errorf("preprocess: %w", err)
+		}
+	}
+
+	c, err := train.New(uint(len(X[0])), numClasses(y), opts)
+	if err != nil {
+		return err
+	}
+	c.SetPreprocess(pre)
+
+	log := e.Logging
+	var dash *dashboard.Dashboard
+	if log.Dashboard != "" {
+		title := "Training on " + datasetName(e.Dataset)
+		if e.Name != "" {
+			title = e.Name
+		}
+		dash = dashboard.New(title)
+		url, err := dash.Listen(log.Dashboard)
+		if err != nil {
+			return err
+		}
+		defer dash.Close()
+
+		fmt.Fprintf(env.Stdout, "Dashboard at %s\n", url)
+	}
+
+	var history train.History
+	opts.History = &history
+	opts.OnStep = func(s train.Step) {
+		last := s.Step == opts.Steps-1
+		if last || (log.Every > 0 && s.Step%log.Every == 0) {
+			line := fmt.Sprintf("Step %d %s", s.Step, formatMetrics(s.Train))
+			if s.Val != nil {
+				line += ", validation " + formatMetrics(*s.Val)
+			}
+			fmt.Fprintln(env.Stdout, line)
+		}
+
+		if dash == nil {
+			return
+		}
+		dash.Record(s)
+
+		// OnStep is called after the update, so the boundary is of the
+		// updated model while the metrics are from before it
+		if len(X[0]) == 2 && (last || s.Step%10 == 9) {
+			var png bytes.Buffer
+			err := viz.WriteBoundary(&png, "png", c.Context(), c.Module(), X, y, viz.BoundaryOptions{})
+			if err == nil {
+				dash.SetBoundary(png.Bytes())
+			}
+		}
+	}
+
+	if err := c.Train(trainX, trainY, opts); err != nil {
+		return err
+	}
+
+	if err := mkdirFor(log.Checkpoint); err != nil {
+		return err
+	}
+	if err := c.SaveFile(log.Checkpoint); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "Saved %s\n", log.Checkpoint)
+
+	if log.History != "" {
+		if err := mkdirFor(log.History); err != nil {
+			return err
+		}
+		if err := viz.PlotHistory(log.History, &history); err != nil {
+			return err
+		}
+	}
+
+	if log.Boundary != "" {
+		if err := mkdirFor(log.Boundary); err != nil {
+			return err
+		}
+		if err := viz.DecisionBoundary(log.Boundary, c.Context(), c.Module(), X, y, viz.BoundaryOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if dash != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		fmt.Fprintln(env.Stdout, "Press Ctrl+C to stop the dashboard")
+		<-ctx.Done()
+	}
+
+	return nil
+}
+
+func runEval(env *Env, args []string) error {
+	fs := env.Flags("eval", "")
+	e := config.Default()
+	model := fs.String("model", "", "checkpoint file, default the experiment's checkpoint")
+	registerDataset(fs, &e.Dataset)
+	configPath := fs.String("config", "", "YAML or JSON experiment file whose dataset and checkpoint are used, flags given with it override them")
+
+	if err := env.parseExperiment(fs, &e, configPath, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return env.usagef(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	if *model == "" {
+		*model = e.Logging.Checkpoint
+	}
+
+	c, err := train.LoadFile(*model)
+	if err != nil {
+		return err
+	}
+
+	X, y, err := e.Dataset.Load()
+	if err != nil {
+		return err
+	}
+
+	m, err := c.Evaluate(X, y)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Stdout, "%d samples, %s\n", len(y), formatMetrics(m))
+
+	return nil
+}
+
+func runPredict(env *Env, args []string) error {
+	fs := env.Flags("predict", "[sample...]")
+	model := fs.String("model", "out/model.json", "checkpoint file")
+	path := fs.String("data", "-", "CSV or TSV file of samples with a header row, - for stdin, ignored if samples are given as arguments")
+	target := fs.String("target", "", "column of -data to ignore, e.g. label")
+	fs.Usage = func() {
+		fmt.Fprintf(env.Stderr, "usage: %s predict [flags] [sample...]\n\n", Name)
+		fmt.Fprintf(env.Stderr, "Each sample is a comma separated list of features, e.g. 0.5,-1\n\nflags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := env.Parse(fs, args); err != nil {
+		return err
+	}
+
+	c, err := train.LoadFile(*model)
+	if err != nil {
+		return err
+	}
+
+	var X [][]float64
+	for _, arg := range fs.Args() {
+		x, err := parseFloats(arg)
+		if err != nil {
+			return env.usagef(fs, "sample %q: %v", arg, err)
+		}
+		X = append(X, x)
+	}
+
+	if len(X) < 1 {
+		opts := data.CSVOptions{Header: true, Target: *target}
+
+		var ds *data.Dataset
+		if *path == "-" {
+			ds, err = data.ReadCSV(env.Stdin, opts)
+		} else {
+			ds, err = data.LoadCSV(*path, opts)
+		}
+		if err != nil {
+			return fmt.Errorf("predict: %w", err)
+		}
+
+		X = ds.X
+	}
+
+	var b strings.Builder
+	for i, x := range X {
+		class, err := c.Predict(x)
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i, err)
+		}
+		fmt.Fprintln(&b, class)
+	}
+
+	_, err = fmt.Fprint(env.Stdout, b.String())
+
+	return err
+}
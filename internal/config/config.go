@@ -0,0 +1,289 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/grad"
+	"github.com/richiejp/micrograd/internal/train"
+)
+
+// Experiment describes a training run so that it can be repeated and
+// compared with others. The names of the fields in files are snake
+// case, e.g. learning_rate.
+type Experiment struct {
+	Name      string    `yaml:"name,omitempty" json:"name,omitempty"`
+	Dataset   Dataset   `yaml:"dataset" json:"dataset"`
+	Model     Model     `yaml:"model" json:"model"`
+	Loss      Loss      `yaml:"loss" json:"loss"`
+	Optimizer Optimizer `yaml:"optimizer" json:"optimizer"`
+	Schedule  Schedule  `yaml:"schedule" json:"schedule"`
+	Logging   Logging   `yaml:"logging" json:"logging"`
+}
+
+// Dataset is either a generated dataset or a CSV or TSV file
+type Dataset struct {
+	// Name of the generated dataset, see data.GeneratorNames. It is
+	// ignored if Path is set.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Path of a file with a header row
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Target is the column of Path holding the class labels
+	Target   string  `yaml:"target,omitempty" json:"target,omitempty"`
+	Samples  int     `yaml:"samples" json:"samples"`
+	Noise    float64 `yaml:"noise" json:"noise"`
+	Classes  int     `yaml:"classes" json:"classes"`
+	Features int     `yaml:"features" json:"features"`
+	Seed     int64   `yaml:"seed" json:"seed"`
+	// CategoricalTarget maps the labels in Target, such as names, to
+	// classes in sorted order. Otherwise they must be integers from 0.
+	CategoricalTarget bool `yaml:"categorical_target,omitempty" json:"categorical_target,omitempty"`
+	// Val is the fraction held out for validation
+	Val float64 `yaml:"val" json:"val"`
+	// Scale is the feature scaling, one of standard, min_max, robust or
+	// none
+	Scale string `yaml:"scale" json:"scale"`
+}
+
+type Model struct {
+	Hidden     []uint     `yaml:"hidden,flow" json:"hidden"`
+	Activation grad.ActFn `yaml:"activation" json:"activation"`
+	// Seed for the parameter initialisation
+	Seed uint64 `yaml:"seed" json:"seed"`
+}
+
+// Loss is the regularization added to the data loss, which is the SVM
+// max-margin loss with two classes and cross-entropy with more
+type Loss struct {
+	L2 float64 `yaml:"l2" json:"l2"`
+}
+
+type Optimizer struct {
+	Name grad.Optim `yaml:"name" json:"name"`
+	// LearningRate of 0 is the optimizer's default
+	LearningRate float64 `yaml:"learning_rate" json:"learning_rate"`
+	// ClipNorm is the global gradient norm, 0 for no clipping
+	ClipNorm float64 `yaml:"clip_norm" json:"clip_norm"`
+}
+
+type Schedule struct {
+	Epochs int `yaml:"epochs" json:"epochs"`
+	// FinalLearningRate, if above zero, is decayed to linearly
+	FinalLearningRate float64 `yaml:"final_learning_rate" json:"final_learning_rate"`
+}
+
+// Logging says what is reported during training and where the results
+// are written, empty paths are not written
+type Logging struct {
+	// Every is the number of steps between printing metrics, 0 for only
+	// the last
+	Every      int    `yaml:"every" json:"every"`
+	Checkpoint string `yaml:"checkpoint" json:"checkpoint"`
+	History    string `yaml:"history,omitempty" json:"history,omitempty"`
+	Boundary   string `yaml:"boundary,omitempty" json:"boundary,omitempty"`
+	// Dashboard is the address to serve a live dashboard on
+	Dashboard string `yaml:"dashboard,omitempty" json:"dashboard,omitempty"`
+}
+
+// Scales are the choices of feature scaling, other than none they are
+// data.NewTransformer types
+var Scales = []string{"standard", "min_max", "robust", "none"}
+
+// Default is the experiment used for anything a file leaves out
+func Default() Experiment {
+	return Experiment{
+		Dataset: Dataset{
+			Name:     "moons",
+			Target:   "label",
+			Samples:  200,
+			Noise:    -1,
+			Classes:  3,
+			Features: 2,
+			Seed:     1,
+			Val:      0.2,
+			Scale:    "standard",
+		},
+		Model: Model{
+			Hidden:     []uint{16, 16},
+			Activation: grad.ReluActFn,
+			Seed:       1,
+		},
+		Loss: Loss{
+			L2: 1e-4,
+		},
+		Optimizer: Optimizer{
+			Name:     grad.SGDOptim,
+			ClipNorm: 5,
+		},
+		Schedule: Schedule{
+			Epochs: 100,
+		},
+		Logging: Logging{
+			Every:      10,
+			Checkpoint: "out/model.json",
+		},
+	}
+}
+
+// Options are the settings of e for train.New and Classifier.Train
+func (e *Experiment) Options() train.Options {
+	return train.Options{
+		Hidden:            e.Model.Hidden,
+		ActFn:             e.Model.Activation,
+		Steps:             e.Schedule.Epochs,
+		Optimizer:         e.Optimizer.Name,
+		LearningRate:      e.Optimizer.LearningRate,
+		FinalLearningRate: e.Schedule.FinalLearningRate,
+		L2:                e.Loss.L2,
+		ClipNorm:          e.Optimizer.ClipNorm,
+		Seed:              e.Model.Seed,
+	}
+}
+
+// Format is the encoding of an experiment file
+type Format string
+
+const (
+	YAML Format = "yaml"
+	JSON Format = "json"
+)
+
+// FormatOf chooses the format from a file's extension, JSON for .json
+// and YAML otherwise
+func FormatOf(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return JSON
+	}
+
+	return YAML
+}
+
+// Read decodes an experiment on top of the defaults and validates it.
+// Unknown fields are an error so that typos aren't silently ignored.
+func Read(r io.Reader, format Format) (*Experiment, error) {
+	e := Default()
+	// Lists replace the default instead of being merged into it
+	e.Model.Hidden = nil
+
+	switch format {
+	case JSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			return nil, err
+		}
+	case YAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&e); err != nil && err != io.EOF {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if e.Model.Hidden == nil {
+		e.Model.Hidden = Default().Model.Hidden
+	}
+
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// Load reads an experiment from a YAML or JSON file
+func Load(path string) (*Experiment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	e, err := Read(file, FormatOf(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return e, nil
+}
+
+// Write encodes the experiment, e.g. to save the settings of a run
+// given on the command line
+func (e *Experiment) Write(w io.Writer, format Format) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Validate reports every problem with the experiment, each prefixed
+// with the field's path, e.g. "model.hidden[1]"
+func (e *Experiment) Validate() error {
+	var errs []error
+	check := func(ok bool, field string, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	d := e.Dataset
+	if d.Path == "" {
+		names := data.GeneratorNames()
+		check(slices.Contains(names, d.Name), "dataset.name", "unknown dataset %q, want one of %s", d.Name, strings.Join(names, ", "))
+		check(d.Samples >= 1, "dataset.samples", "need at least one sample, not %d", d.Samples)
+		check(d.Classes >= 2, "dataset.classes", "need at least two classes, not %d", d.Classes)
+		check(d.Features >= 1, "dataset.features", "need at least one feature, not %d", d.Features)
+	} else {
+		check(d.Target != "", "dataset.target", "needed with a path")
+	}
+	check(d.Val >= 0 && d.Val < 1, "dataset.val", "must be in [0, 1), not %v", d.Val)
+	check(slices.Contains(Scales, d.Scale), "dataset.scale", "unknown scaling %q, want one of %s", d.Scale, strings.Join(Scales, ", "))
+
+	m := e.Model
+	for i, n := range m.Hidden {
+		check(n >= 1, fmt.Sprintf("model.hidden[%d]", i), "layers need at least one neuron")
+	}
+	switch m.Activation {
+	case grad.ReluActFn, grad.TanhActFn, grad.LinearActFn:
+	default:
+		check(false, "model.activation", "unknown activation %q, want one of relu, tanh or linear", m.Activation)
+	}
+
+	check(e.Loss.L2 >= 0, "loss.l2", "must not be negative")
+
+	o := e.Optimizer
+	_, err := grad.NewOptimizer[float64](o.Name)
+	check(err == nil, "optimizer.name", "unknown optimizer %q, want one of sgd, momentum or adam", o.Name)
+	check(o.LearningRate >= 0, "optimizer.learning_rate", "must not be negative")
+	check(o.ClipNorm >= 0, "optimizer.clip_norm", "must not be negative")
+
+	check(e.Schedule.Epochs >= 1, "schedule.epochs", "need at least one epoch, not %d", e.Schedule.Epochs)
+	check(e.Schedule.FinalLearningRate >= 0, "schedule.final_learning_rate", "must not be negative")
+
+	check(e.Logging.Every >= 0, "logging.every", "must not be negative")
+	check(e.Logging.Checkpoint != "", "logging.checkpoint", "a path is needed")
+
+	return errors.Join(errs...)
+}
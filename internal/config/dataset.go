@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/richiejp/micrograd/internal/data"
+)
+
+// Load reads the dataset's file, or generates it if it has no path
+func (d Dataset) Load() ([][]float64, []int, error) {
+	if d.Path == "" {
+		return data.Generate(d.Name, data.GenerateOptions{
+			Samples:  d.Samples,
+			Noise:    d.Noise,
+			Classes:  d.Classes,
+			Features: d.Features,
+			Seed:     d.Seed,
+		})
+	}
+
+	ds, err := data.LoadCSV(d.Path, data.CSVOptions{
+		Header:            true,
+		Target:            d.Target,
+		CategoricalTarget: d.CategoricalTarget,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if d.CategoricalTarget {
+		return ds.X, ds.Labels, nil
+	}
+
+	y, err := labels(ds.Y)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", d.Path, err)
+	}
+
+	return ds.X, y, nil
+}
+
+// labels converts a numeric target to classes
+func labels(Y []float64) ([]int, error) {
+	y := make([]int, len(Y))
+	for i, v := range Y {
+		if v < 0 || v != math.Trunc(v) || v > math.MaxInt32 {
+			return nil, fmt.Errorf("sample %d: label %v is not a class, classes are integers from 0", i, v)
+		}
+		y[i] = int(v)
+	}
+
+	return y, nil
+}
+
+// Split holds out the Val fraction of X and y, stratified by class, the
+// validation set is empty if Val is 0
+func (d Dataset) Split(X [][]float64, y []int) (trainX [][]float64, trainY []int, valX [][]float64, valY []int, err error) {
+	if d.Val <= 0 {
+		return X, y, nil, nil, nil
+	}
+
+	trainIdx, valIdx, err := data.TrainTestSplit(y, d.Val, true, d.Seed)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	trainX, trainY = data.Take(X, y, trainIdx)
+	valX, valY = data.Take(X, y, valIdx)
+
+	return trainX, trainY, valX, valY, nil
+}
+
+// Preprocess returns an unfitted pipeline for the dataset's Scale, nil
+// for none
+func (d Dataset) Preprocess() (data.Pipeline, error) {
+	if d.Scale == "none" {
+		return nil, nil
+	}
+
+	s, err := data.NewTransformer(d.Scale)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Pipeline{s}, nil
+}
@@ -0,0 +1,246 @@
+package dashboard
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/richiejp/micrograd/internal/train"
+)
+
+//go:embed dashboard.html
+var page []byte
+
+// bufferSize is the number of events a client may fall behind by before
+// it is disconnected, the page then reconnects and reloads the history
+const bufferSize = 256
+
+type event struct {
+	name string
+	data []byte
+}
+
+// Dashboard serves a page which follows training as it runs, with
+// charts of the steps and the latest decision boundary image. Its
+// methods may be called from any goroutine.
+type Dashboard struct {
+	mu       sync.Mutex
+	title    string
+	history  train.History
+	boundary []byte
+	// version counts the boundary updates so browsers reload the image
+	version int
+	clients map[chan event]struct{}
+	server  *http.Server
+}
+
+func New(title string) *Dashboard {
+	return &Dashboard{
+		title:   title,
+		clients: make(map[chan event]struct{}),
+	}
+}
+
+// broadcast sends e to each client without waiting, clients which are
+// too slow are dropped. The lock must be held.
+func (d *Dashboard) broadcast(e event) {
+	for ch := range d.clients {
+		select {
+		case ch <- e:
+		default:
+			delete(d.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// Record adds a step, it can be used as train.Options.OnStep
+func (d *Dashboard) Record(s train.Step) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.history.Record(s)
+
+	// Steps that can't be encoded, e.g. with an infinite loss, are still
+	// in the history but the charts skip them
+	if b, err := json.Marshal(s); err == nil {
+		d.broadcast(event{name: "step", data: b})
+	}
+}
+
+// SetBoundary replaces the decision boundary image, which must be a PNG,
+// e.g. from viz.WriteBoundary
+func (d *Dashboard) SetBoundary(png []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.boundary = png
+	d.version++
+	d.broadcast(event{name: "boundary", data: []byte(strconv.Itoa(d.version))})
+}
+
+// History returns a copy of the steps recorded so far
+func (d *Dashboard) History() train.History {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return train.History{Steps: append([]train.Step(nil), d.history.Steps...)}
+}
+
+func (d *Dashboard) subscribe() chan event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ch := make(chan event, bufferSize)
+	d.clients[ch] = struct{}{}
+
+	return ch
+}
+
+func (d *Dashboard) unsubscribe(ch chan event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.clients[ch]; ok {
+		delete(d.clients, ch)
+		close(ch)
+	}
+}
+
+func (d *Dashboard) serveHistory(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	steps := make([]json.RawMessage, 0, len(d.history.Steps))
+	for _, s := range d.history.Steps {
+		if b, err := json.Marshal(s); err == nil {
+			steps = append(steps, b)
+		}
+	}
+	b, err := json.Marshal(struct {
+		Title    string            `json:"title"`
+		Steps    []json.RawMessage `json:"steps"`
+		Boundary int               `json:"boundary"`
+	}{
+		Title:    d.title,
+		Steps:    steps,
+		Boundary: d.version,
+	})
+	d.mu.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(b)
+}
+
+func (d *Dashboard) serveBoundary(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	png := d.boundary
+	d.mu.Unlock()
+
+	if png == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+// serveEvents streams new steps and boundary updates as server-sent
+// events
+func (d *Dashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := d.subscribe()
+	defer d.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Handler serves the page at /, the recorded history at /history, a
+// stream of updates at /events and the decision boundary at
+// /boundary.png
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	mux.HandleFunc("GET /history", d.serveHistory)
+	mux.HandleFunc("GET /events", d.serveEvents)
+	mux.HandleFunc("GET /boundary.png", d.serveBoundary)
+
+	return mux
+}
+
+// Listen serves the dashboard on addr, e.g. "localhost:8080", in the
+// background and returns its URL. A port of 0 picks a free port.
+func (d *Dashboard) Listen(addr string) (string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("dashboard: %w", err)
+	}
+
+	srv := &http.Server{Handler: d.Handler()}
+
+	d.mu.Lock()
+	if d.server != nil {
+		d.mu.Unlock()
+		ln.Close()
+		return "", errors.New("dashboard: already listening")
+	}
+	d.server = srv
+	d.mu.Unlock()
+
+	go srv.Serve(ln)
+
+	return "http://" + ln.Addr().String() + "/", nil
+}
+
+// Close stops the server and disconnects the clients
+func (d *Dashboard) Close() error {
+	d.mu.Lock()
+	srv := d.server
+	d.server = nil
+	d.mu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+
+	return srv.Close()
+}
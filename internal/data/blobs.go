@@ -0,0 +1,52 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// MakeBlobs generates isotropic Gaussian blobs, one class per centre.
+// nSamples: total number of points, spread evenly between the centres
+// centers: the mean of each blob, all must have the same dimension
+// std: standard deviation of each blob
+// seed: seed for the random number generator
+func MakeBlobs(nSamples int, centers [][]float64, std float64, seed int64) ([][]float64, []int, error) {
+	if len(centers) < 1 {
+		return nil, nil, errors.New("make blobs: need at least one centre")
+	}
+	for i, c := range centers {
+		if len(c) != len(centers[0]) {
+			return nil, nil, fmt.Errorf("make blobs: centre %d has %d dimensions, centre 0 has %d", i, len(c), len(centers[0]))
+		}
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+
+	X := make([][]float64, nSamples)
+	y := make([]int, nSamples)
+
+	for i := range nSamples {
+		// Spread any remainder over the first centres like scikit-learn
+		class := i * len(centers) / nSamples
+		center := centers[class]
+
+		X[i] = make([]float64, len(center))
+		for j, c := range center {
+			X[i][j] = c + std*randNorm(rng)
+		}
+		y[i] = class
+	}
+
+	shuffleXY(rng, X, y)
+
+	return X, y, nil
+}
+
+func shuffleXY[Y any](rng *rand.Rand, X [][]float64, y []Y) {
+	for i := len(X) - 1; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		X[i], X[j] = X[j], X[i]
+		y[i], y[j] = y[j], y[i]
+	}
+}
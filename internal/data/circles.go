@@ -0,0 +1,39 @@
+package data
+
+import (
+	"math"
+	"math/rand"
+)
+
+// MakeCircles generates a large circle (class 0) containing a smaller
+// one (class 1).
+// nSamples: total number of points
+// noise: standard deviation of Gaussian noise (set to 0.0 for no noise)
+// factor: scale of the inner circle relative to the outer, in (0, 1)
+// seed: seed for the random number generator
+func MakeCircles(nSamples int, noise float64, factor float64, seed int64) ([][]float64, []int) {
+	rng := rand.New(rand.NewSource(seed))
+
+	nSamplesOut := nSamples / 2
+	nSamplesIn := nSamples - nSamplesOut
+
+	X := make([][]float64, 0, nSamples)
+	y := make([]int, 0, nSamples)
+
+	circle := func(n int, radius float64, class int) {
+		for i := range n {
+			theta := 2 * math.Pi * float64(i) / float64(n)
+			X = append(X, []float64{
+				radius*math.Cos(theta) + noise*randNorm(rng),
+				radius*math.Sin(theta) + noise*randNorm(rng),
+			})
+			y = append(y, class)
+		}
+	}
+	circle(nSamplesOut, 1, 0)
+	circle(nSamplesIn, factor, 1)
+
+	shuffleXY(rng, X, y)
+
+	return X, y
+}
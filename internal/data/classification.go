@@ -0,0 +1,71 @@
+package data
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// MakeClassification generates a random n-class problem similar to
+// scikit-learn's make_classification. Each class is a Gaussian cluster
+// centred on a vertex of a hypercube in the informative features. The
+// redundant features are random linear combinations of the informative
+// ones and the remaining features are noise. Features are in the order
+// informative, redundant then noise.
+// nSamples: total number of points, spread evenly between the classes
+// nFeatures: total number of features
+// nInformative: number of features the classes are separated in
+// nRedundant: number of linear combinations of the informative features
+// nClasses: number of classes, at most 2^nInformative
+// classSep: distance of the hypercube vertices from the origin
+// seed: seed for the random number generator
+func MakeClassification(nSamples, nFeatures, nInformative, nRedundant, nClasses int, classSep float64, seed int64) ([][]float64, []int, error) {
+	if nInformative < 1 || nRedundant < 0 || nInformative+nRedundant > nFeatures {
+		return nil, nil, fmt.Errorf("make classification: %d informative and %d redundant features do not fit in %d", nInformative, nRedundant, nFeatures)
+	}
+	if nClasses < 1 || (nInformative < 31 && nClasses > 1<<nInformative) {
+		return nil, nil, fmt.Errorf("make classification: %d classes need more than %d informative features", nClasses, nInformative)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+
+	mixing := make([][]float64, nRedundant)
+	for r := range mixing {
+		mixing[r] = make([]float64, nInformative)
+		for j := range mixing[r] {
+			mixing[r][j] = 2*rng.Float64() - 1
+		}
+	}
+
+	X := make([][]float64, nSamples)
+	y := make([]int, nSamples)
+
+	for i := range nSamples {
+		class := i * nClasses / nSamples
+		row := make([]float64, nFeatures)
+
+		for j := range nInformative {
+			vertex := -classSep
+			if class&(1<<j) != 0 {
+				vertex = classSep
+			}
+			row[j] = vertex + randNorm(rng)
+		}
+
+		for r, weights := range mixing {
+			for j, w := range weights {
+				row[nInformative+r] += w * row[j]
+			}
+		}
+
+		for j := nInformative + nRedundant; j < nFeatures; j++ {
+			row[j] = randNorm(rng)
+		}
+
+		X[i] = row
+		y[i] = class
+	}
+
+	shuffleXY(rng, X, y)
+
+	return X, y, nil
+}
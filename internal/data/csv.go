@@ -0,0 +1,336 @@
+package data
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MissingPolicy says what to do with empty or "NA" fields
+type MissingPolicy int
+
+const (
+	// MissingError fails the load
+	MissingError MissingPolicy = iota
+	// MissingSkipRow drops any row with a missing field
+	MissingSkipRow
+	// MissingZero replaces missing numeric features with zero
+	MissingZero
+	// MissingMean replaces missing numeric features with the column mean
+	MissingMean
+)
+
+var ErrMissingValue = errors.New("missing value")
+
+var defaultMissingValues = []string{"", "NA", "N/A", "?"}
+
+type CSVOptions struct {
+	// Comma is the field separator, it defaults to a tab for .tsv files
+	// and a comma otherwise
+	Comma rune
+	// Header means the first row contains the column names. Without a
+	// header the columns are named by their index starting from "0".
+	Header bool
+	// Features are the names of the feature columns. If empty then
+	// every column except the target is used.
+	Features []string
+	// Target is the name of the target column, empty for none
+	Target string
+	// CategoricalTarget encodes the target as class labels instead of
+	// parsing it as a number
+	CategoricalTarget bool
+	// Categorical names feature columns to encode as integer codes
+	Categorical []string
+	// Missing is the policy for missing values. Zero and Mean only apply
+	// to numeric features, elsewhere a missing value is an error unless
+	// the row is skipped.
+	Missing MissingPolicy
+	// MissingValues are the fields treated as missing, they default to
+	// "", "NA", "N/A" and "?"
+	MissingValues []string
+}
+
+// Dataset is a table of features loaded from a file
+type Dataset struct {
+	Features []string
+	X        [][]float64
+	// Y is the numeric target, nil if there is no target or it is
+	// categorical
+	Y []float64
+	// Labels is the categorical target, each label indexes Classes
+	Labels  []int
+	Classes []string
+	// Categories lists the values of each categorical feature, the
+	// feature's value in X is an index into its list
+	Categories map[string][]string
+}
+
+// ParseError reports a field which could not be used. Row is the line
+// in the file, starting from 1.
+type ParseError struct {
+	Row    int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("row %d, column %q: value %q: %v", e.Row, e.Column, e.Value, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// LoadCSV reads a CSV or TSV file, see ReadCSV
+func LoadCSV(path string, opts CSVOptions) (*Dataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if opts.Comma == 0 && strings.EqualFold(filepath.Ext(path), ".tsv") {
+		opts.Comma = '\t'
+	}
+
+	ds, err := ReadCSV(file, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return ds, nil
+}
+
+// ReadTSV is ReadCSV with tab separated fields
+func ReadTSV(r io.Reader, opts CSVOptions) (*Dataset, error) {
+	opts.Comma = '\t'
+
+	return ReadCSV(r, opts)
+}
+
+func ReadCSV(r io.Reader, opts CSVOptions) (*Dataset, error) {
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.TrimLeadingSpace = true
+
+	var records [][]string
+	var lines []int
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		records = append(records, rec)
+		lines = append(lines, line)
+	}
+
+	if len(records) < 1 {
+		return nil, errors.New("no rows")
+	}
+
+	var names []string
+	if opts.Header {
+		names = records[0]
+		records = records[1:]
+		lines = lines[1:]
+	} else {
+		for i := range records[0] {
+			names = append(names, strconv.Itoa(i))
+		}
+	}
+
+	column := func(name string) (int, error) {
+		i := slices.Index(names, name)
+		if i < 0 {
+			return 0, fmt.Errorf("no column %q", name)
+		}
+		return i, nil
+	}
+
+	target := -1
+	if opts.Target != "" {
+		var err error
+		if target, err = column(opts.Target); err != nil {
+			return nil, err
+		}
+	}
+
+	var features []int
+	if len(opts.Features) > 0 {
+		for _, name := range opts.Features {
+			i, err := column(name)
+			if err != nil {
+				return nil, err
+			}
+			features = append(features, i)
+		}
+	} else {
+		for i := range names {
+			if i != target {
+				features = append(features, i)
+			}
+		}
+	}
+
+	categorical := make(map[int]bool)
+	for _, name := range opts.Categorical {
+		i, err := column(name)
+		if err != nil {
+			return nil, err
+		}
+		categorical[i] = true
+	}
+
+	missingValues := opts.MissingValues
+	if missingValues == nil {
+		missingValues = defaultMissingValues
+	}
+	isMissing := func(field string) bool {
+		return slices.Contains(missingValues, strings.TrimSpace(field))
+	}
+
+	ds := Dataset{
+		Categories: make(map[string][]string),
+	}
+	for _, j := range features {
+		ds.Features = append(ds.Features, names[j])
+	}
+
+	// Categorical values are encoded after every row has been read so
+	// that the codes are sorted and don't depend on row order
+	var rows [][]string
+	var missing [][]bool
+
+rows:
+	for r, rec := range records {
+		x := make([]float64, len(features))
+		miss := make([]bool, len(features))
+
+		if target >= 0 && isMissing(rec[target]) {
+			if opts.Missing == MissingSkipRow {
+				continue
+			}
+			return nil, &ParseError{Row: lines[r], Column: names[target], Value: rec[target], Err: ErrMissingValue}
+		}
+
+		for k, j := range features {
+			field := rec[j]
+
+			if isMissing(field) {
+				switch {
+				case opts.Missing == MissingSkipRow:
+					continue rows
+				case opts.Missing == MissingError || categorical[j]:
+					return nil, &ParseError{Row: lines[r], Column: names[j], Value: field, Err: ErrMissingValue}
+				}
+				miss[k] = true
+				continue
+			}
+
+			if categorical[j] {
+				continue
+			}
+
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, &ParseError{Row: lines[r], Column: names[j], Value: field, Err: err}
+			}
+			x[k] = v
+		}
+
+		if target >= 0 && !opts.CategoricalTarget {
+			v, err := strconv.ParseFloat(strings.TrimSpace(rec[target]), 64)
+			if err != nil {
+				return nil, &ParseError{Row: lines[r], Column: names[target], Value: rec[target], Err: err}
+			}
+			ds.Y = append(ds.Y, v)
+		}
+
+		ds.X = append(ds.X, x)
+		rows = append(rows, rec)
+		missing = append(missing, miss)
+	}
+
+	for k, j := range features {
+		if !categorical[j] {
+			continue
+		}
+
+		codes, values := encode(rows, j)
+		ds.Categories[names[j]] = values
+		for i, code := range codes {
+			ds.X[i][k] = float64(code)
+		}
+	}
+
+	if target >= 0 && opts.CategoricalTarget {
+		ds.Labels, ds.Classes = encode(rows, target)
+	}
+
+	if opts.Missing == MissingMean {
+		imputeMean(ds.X, missing)
+	}
+
+	return &ds, nil
+}
+
+// encode gives each distinct value in column j a code in sorted order
+func encode(rows [][]string, j int) ([]int, []string) {
+	var values []string
+	for _, rec := range rows {
+		values = append(values, strings.TrimSpace(rec[j]))
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	codes := make([]int, len(values))
+	for i, v := range values {
+		codes[i], _ = slices.BinarySearch(sorted, v)
+	}
+
+	return codes, sorted
+}
+
+func imputeMean(X [][]float64, missing [][]bool) {
+	if len(X) < 1 {
+		return
+	}
+
+	for k := range X[0] {
+		var sum float64
+		var n int
+		for i, x := range X {
+			if !missing[i][k] {
+				sum += x[k]
+				n++
+			}
+		}
+
+		mean := 0.0
+		if n > 0 {
+			mean = sum / float64(n)
+		}
+
+		for i, x := range X {
+			if missing[i][k] {
+				x[k] = mean
+			}
+		}
+	}
+}
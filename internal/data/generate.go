@@ -0,0 +1,97 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// GenerateOptions configure Generate, fields which a dataset doesn't
+// use are ignored
+type GenerateOptions struct {
+	Samples int
+	// Noise is negative for the dataset's default. For classification
+	// it is the class separation.
+	Noise float64
+	// Classes for blobs, spirals and classification
+	Classes int
+	// Features for classification
+	Features int
+	Seed     int64
+}
+
+type generator struct {
+	noise float64
+	// classes is set if the generator uses GenerateOptions.Classes
+	classes bool
+	make    func(o GenerateOptions) ([][]float64, []int, error)
+}
+
+var generators = map[string]generator{
+	"moons": {noise: 0.1, make: func(o GenerateOptions) ([][]float64, []int, error) {
+		X, y := MakeMoonsSeeded(o.Samples, o.Noise, true, o.Seed)
+		return X, y, nil
+	}},
+	"circles": {noise: 0.1, make: func(o GenerateOptions) ([][]float64, []int, error) {
+		X, y := MakeCircles(o.Samples, o.Noise, 0.5, o.Seed)
+		return X, y, nil
+	}},
+	"blobs": {noise: 0.8, classes: true, make: func(o GenerateOptions) ([][]float64, []int, error) {
+		// Centres evenly spaced on a circle
+		centers := make([][]float64, o.Classes)
+		for i := range centers {
+			theta := 2 * math.Pi * float64(i) / float64(o.Classes)
+			centers[i] = []float64{3 * math.Cos(theta), 3 * math.Sin(theta)}
+		}
+		return MakeBlobs(o.Samples, centers, o.Noise, o.Seed)
+	}},
+	"xor": {noise: 0.1, make: func(o GenerateOptions) ([][]float64, []int, error) {
+		X, y := MakeXOR(o.Samples, o.Noise, o.Seed)
+		return X, y, nil
+	}},
+	"spirals": {noise: 0.2, classes: true, make: func(o GenerateOptions) ([][]float64, []int, error) {
+		return MakeSpirals(o.Samples, o.Classes, o.Noise, o.Seed)
+	}},
+	"classification": {noise: 1, classes: true, make: func(o GenerateOptions) ([][]float64, []int, error) {
+		informative := min(o.Features, 2)
+		return MakeClassification(o.Samples, o.Features, informative, 0, o.Classes, o.Noise, o.Seed)
+	}},
+}
+
+// GeneratorNames lists the datasets Generate can make in sorted order
+func GeneratorNames() []string {
+	var names []string
+	for name := range generators {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Generate makes one of the toy classification datasets by name, see
+// GeneratorNames
+func Generate(name string, o GenerateOptions) ([][]float64, []int, error) {
+	gen, ok := generators[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown dataset %q, want one of %v", name, GeneratorNames())
+	}
+
+	if o.Samples < 1 {
+		return nil, nil, errors.New("need at least one sample")
+	}
+	if gen.classes && o.Classes < 2 {
+		return nil, nil, errors.New("need at least two classes")
+	}
+	if o.Noise < 0 {
+		o.Noise = gen.noise
+	}
+
+	X, y, err := gen.make(o)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return X, y, nil
+}
@@ -13,6 +13,12 @@ import (
 // noise: standard deviation of Gaussian noise (set to 0.0 for no noise)
 // shuffle: whether to shuffle the samples
 func MakeMoons(nSamples int, noise float64, shuffle bool) ([][]float64, []int) {
+	return MakeMoonsSeeded(nSamples, noise, shuffle, time.Now().UnixNano())
+}
+
+// MakeMoonsSeeded is MakeMoons with an explicit seed for the random
+// number generator
+func MakeMoonsSeeded(nSamples int, noise float64, shuffle bool, seed int64) ([][]float64, []int) {
 	nSamplesOut := nSamples / 2
 	nSamplesIn := nSamples - nSamplesOut
 
@@ -36,9 +42,10 @@ func MakeMoons(nSamples int, noise float64, shuffle bool) ([][]float64, []int) {
 		y[nSamplesOut+i] = 1
 	}
 
+	rng := rand.New(rand.NewSource(seed))
+
 	// Add Gaussian noise if needed
 	if noise > 0.0 {
-		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 		for i := range nSamples {
 			X[i][0] += noise * randNorm(rng)
 			X[i][1] += noise * randNorm(rng)
@@ -47,12 +54,7 @@ func MakeMoons(nSamples int, noise float64, shuffle bool) ([][]float64, []int) {
 
 	// Shuffle samples if needed
 	if shuffle {
-		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-		for i := nSamples - 1; i > 0; i-- {
-			j := rng.Intn(i + 1)
-			X[i], X[j] = X[j], X[i]
-			y[i], y[j] = y[j], y[i]
-		}
+		shuffleXY(rng, X, y)
 	}
 
 	return X, y
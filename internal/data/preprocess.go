@@ -0,0 +1,497 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Transformer is a preprocessing step which learns its parameters from
+// the training data with Fit and then applies them to any data with
+// Transform. Transform does not modify its input.
+type Transformer interface {
+	Fit(X [][]float64) error
+	Transform(X [][]float64) ([][]float64, error)
+}
+
+var ErrNotFitted = errors.New("transformer is not fitted")
+
+func FitTransform(t Transformer, X [][]float64) ([][]float64, error) {
+	if err := t.Fit(X); err != nil {
+		return nil, err
+	}
+
+	return t.Transform(X)
+}
+
+func width(X [][]float64) (int, error) {
+	if len(X) < 1 {
+		return 0, errors.New("no samples")
+	}
+
+	for i, x := range X {
+		if len(x) != len(X[0]) {
+			return 0, fmt.Errorf("sample %d has %d features, sample 0 has %d", i, len(x), len(X[0]))
+		}
+	}
+
+	return len(X[0]), nil
+}
+
+func checkWidth(X [][]float64, want int) error {
+	for i, x := range X {
+		if len(x) != want {
+			return fmt.Errorf("sample %d has %d features, fitted on %d", i, len(x), want)
+		}
+	}
+
+	return nil
+}
+
+// affine transforms each feature j to (x - shift[j]) / scale[j]
+func affine(X [][]float64, shift []float64, scale []float64) ([][]float64, error) {
+	if shift == nil {
+		return nil, ErrNotFitted
+	}
+	if err := checkWidth(X, len(shift)); err != nil {
+		return nil, err
+	}
+
+	out := make([][]float64, len(X))
+	for i, x := range X {
+		out[i] = make([]float64, len(x))
+		for j, v := range x {
+			out[i][j] = (v - shift[j]) / scale[j]
+		}
+	}
+
+	return out, nil
+}
+
+func column(X [][]float64, j int) []float64 {
+	col := make([]float64, len(X))
+	for i, x := range X {
+		col[i] = x[j]
+	}
+
+	return col
+}
+
+// nonZero replaces zero scales with one so constant features are left
+// centred rather than divided by zero
+func nonZero(scale []float64) {
+	for j, s := range scale {
+		if s == 0 {
+			scale[j] = 1
+		}
+	}
+}
+
+// StandardScaler scales each feature to zero mean and unit variance
+type StandardScaler struct {
+	Mean  []float64 `json:"mean"`
+	Scale []float64 `json:"scale"`
+}
+
+func (s *StandardScaler) Fit(X [][]float64) error {
+	n, err := width(X)
+	if err != nil {
+		return fmt.Errorf("standard scaler: %w", err)
+	}
+
+	s.Mean = make([]float64, n)
+	s.Scale = make([]float64, n)
+	for j := range n {
+		col := column(X, j)
+
+		for _, v := range col {
+			s.Mean[j] += v / float64(len(col))
+		}
+		for _, v := range col {
+			s.Scale[j] += (v - s.Mean[j]) * (v - s.Mean[j]) / float64(len(col))
+		}
+		s.Scale[j] = math.Sqrt(s.Scale[j])
+	}
+	nonZero(s.Scale)
+
+	return nil
+}
+
+func (s *StandardScaler) Transform(X [][]float64) ([][]float64, error) {
+	out, err := affine(X, s.Mean, s.Scale)
+	if err != nil {
+		return nil, fmt.Errorf("standard scaler: %w", err)
+	}
+
+	return out, nil
+}
+
+// MinMaxScaler scales each feature so that the training data spans
+// [Low, High], which defaults to [0, 1]
+type MinMaxScaler struct {
+	Low     float64   `json:"low"`
+	High    float64   `json:"high"`
+	DataMin []float64 `json:"data_min"`
+	DataMax []float64 `json:"data_max"`
+}
+
+// bounds is the target range, [0, 1] when Low and High are unset
+func (s *MinMaxScaler) bounds() (float64, float64) {
+	if s.Low == 0 && s.High == 0 {
+		return 0, 1
+	}
+
+	return s.Low, s.High
+}
+
+func (s *MinMaxScaler) Fit(X [][]float64) error {
+	n, err := width(X)
+	if err != nil {
+		return fmt.Errorf("min-max scaler: %w", err)
+	}
+
+	low, high := s.bounds()
+	if low >= high {
+		return fmt.Errorf("min-max scaler: range [%v, %v] is empty", low, high)
+	}
+
+	s.DataMin = make([]float64, n)
+	s.DataMax = make([]float64, n)
+	for j := range n {
+		col := column(X, j)
+		s.DataMin[j] = slices.Min(col)
+		s.DataMax[j] = slices.Max(col)
+	}
+
+	return nil
+}
+
+func (s *MinMaxScaler) Transform(X [][]float64) ([][]float64, error) {
+	if s.DataMin == nil {
+		return nil, fmt.Errorf("min-max scaler: %w", ErrNotFitted)
+	}
+
+	// x' = Low + (x - min) * (High - Low) / (max - min)
+	low, high := s.bounds()
+	shift := make([]float64, len(s.DataMin))
+	scale := make([]float64, len(s.DataMin))
+	for j := range shift {
+		scale[j] = (s.DataMax[j] - s.DataMin[j]) / (high - low)
+		if scale[j] == 0 {
+			scale[j] = 1
+		}
+		shift[j] = s.DataMin[j] - low*scale[j]
+	}
+
+	out, err := affine(X, shift, scale)
+	if err != nil {
+		return nil, fmt.Errorf("min-max scaler: %w", err)
+	}
+
+	return out, nil
+}
+
+// RobustScaler centres each feature on its median and scales it by its
+// interquartile range, so it is not thrown off by outliers
+type RobustScaler struct {
+	Center []float64 `json:"center"`
+	Scale  []float64 `json:"scale"`
+}
+
+// quantile interpolates linearly between the closest ranks like numpy
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+
+	return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+func (s *RobustScaler) Fit(X [][]float64) error {
+	n, err := width(X)
+	if err != nil {
+		return fmt.Errorf("robust scaler: %w", err)
+	}
+
+	s.Center = make([]float64, n)
+	s.Scale = make([]float64, n)
+	for j := range n {
+		col := column(X, j)
+		slices.Sort(col)
+
+		s.Center[j] = quantile(col, 0.5)
+		s.Scale[j] = quantile(col, 0.75) - quantile(col, 0.25)
+	}
+	nonZero(s.Scale)
+
+	return nil
+}
+
+func (s *RobustScaler) Transform(X [][]float64) ([][]float64, error) {
+	out, err := affine(X, s.Center, s.Scale)
+	if err != nil {
+		return nil, fmt.Errorf("robust scaler: %w", err)
+	}
+
+	return out, nil
+}
+
+// OneHotEncoder replaces each of the Columns, which should hold category
+// codes such as those made by ReadCSV, with one indicator feature per
+// category. Other features are passed through in their original order,
+// each followed by the indicators if it is encoded. Categories not seen
+// by Fit are an error.
+type OneHotEncoder struct {
+	Columns    []int       `json:"columns"`
+	Categories [][]float64 `json:"categories"`
+	NFeatures  int         `json:"n_features"`
+}
+
+func (e *OneHotEncoder) Fit(X [][]float64) error {
+	n, err := width(X)
+	if err != nil {
+		return fmt.Errorf("one-hot encoder: %w", err)
+	}
+
+	e.NFeatures = n
+	e.Categories = make([][]float64, len(e.Columns))
+	for k, j := range e.Columns {
+		if j < 0 || j >= n {
+			return fmt.Errorf("one-hot encoder: no column %d", j)
+		}
+
+		cats := column(X, j)
+		slices.Sort(cats)
+		e.Categories[k] = slices.Compact(cats)
+	}
+
+	return nil
+}
+
+func (e *OneHotEncoder) Transform(X [][]float64) ([][]float64, error) {
+	if e.Categories == nil {
+		return nil, fmt.Errorf("one-hot encoder: %w", ErrNotFitted)
+	}
+	if err := checkWidth(X, e.NFeatures); err != nil {
+		return nil, fmt.Errorf("one-hot encoder: %w", err)
+	}
+
+	encoded := make(map[int][]float64)
+	for k, j := range e.Columns {
+		encoded[j] = e.Categories[k]
+	}
+
+	out := make([][]float64, len(X))
+	for i, x := range X {
+		for j, v := range x {
+			cats, ok := encoded[j]
+			if !ok {
+				out[i] = append(out[i], v)
+				continue
+			}
+
+			c, found := slices.BinarySearch(cats, v)
+			if !found {
+				return nil, fmt.Errorf("one-hot encoder: sample %d, column %d: unknown category %v", i, j, v)
+			}
+
+			onehot := make([]float64, len(cats))
+			onehot[c] = 1
+			out[i] = append(out[i], onehot...)
+		}
+	}
+
+	return out, nil
+}
+
+// PolynomialFeatures replaces the features with every product of them
+// up to Degree, which defaults to 2. For features a and b with degree 2
+// that is a, b, a^2, ab, b^2. There is no constant term because the
+// neurons have biases.
+type PolynomialFeatures struct {
+	Degree int `json:"degree"`
+	// Powers has the exponent of each input feature for each output
+	Powers [][]int `json:"powers"`
+}
+
+func (p *PolynomialFeatures) Fit(X [][]float64) error {
+	n, err := width(X)
+	if err != nil {
+		return fmt.Errorf("polynomial features: %w", err)
+	}
+
+	if p.Degree == 0 {
+		p.Degree = 2
+	}
+	if p.Degree < 1 {
+		return fmt.Errorf("polynomial features: degree %d", p.Degree)
+	}
+
+	// Build the combinations with replacement of each degree in order
+	p.Powers = nil
+	var build func(start int, left int, powers []int)
+	build = func(start int, left int, powers []int) {
+		if left == 0 {
+			p.Powers = append(p.Powers, slices.Clone(powers))
+			return
+		}
+		for j := start; j < n; j++ {
+			powers[j]++
+			build(j, left-1, powers)
+			powers[j]--
+		}
+	}
+	for d := 1; d <= p.Degree; d++ {
+		build(0, d, make([]int, n))
+	}
+
+	return nil
+}
+
+func (p *PolynomialFeatures) Transform(X [][]float64) ([][]float64, error) {
+	if p.Powers == nil {
+		return nil, fmt.Errorf("polynomial features: %w", ErrNotFitted)
+	}
+	if err := checkWidth(X, len(p.Powers[0])); err != nil {
+		return nil, fmt.Errorf("polynomial features: %w", err)
+	}
+
+	out := make([][]float64, len(X))
+	for i, x := range X {
+		out[i] = make([]float64, len(p.Powers))
+		for k, powers := range p.Powers {
+			prod := 1.0
+			for j, e := range powers {
+				for range e {
+					prod *= x[j]
+				}
+			}
+			out[i][k] = prod
+		}
+	}
+
+	return out, nil
+}
+
+// Pipeline applies transformers in order, each is fitted on the output
+// of the one before. It can be saved as JSON alongside a model so that
+// the same preprocessing is applied at inference time.
+type Pipeline []Transformer
+
+func (p Pipeline) Fit(X [][]float64) error {
+	_, err := p.FitTransform(X)
+
+	return err
+}
+
+func (p Pipeline) FitTransform(X [][]float64) ([][]float64, error) {
+	out := X
+
+	for _, t := range p {
+		var err error
+		if out, err = FitTransform(t, out); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func (p Pipeline) Transform(X [][]float64) ([][]float64, error) {
+	out := X
+
+	for _, t := range p {
+		var err error
+		if out, err = t.Transform(out); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+type pipelineStep struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+var transformers = map[string]func() Transformer{
+	"standard":   func() Transformer { return &StandardScaler{} },
+	"min_max":    func() Transformer { return &MinMaxScaler{} },
+	"robust":     func() Transformer { return &RobustScaler{} },
+	"one_hot":    func() Transformer { return &OneHotEncoder{} },
+	"polynomial": func() Transformer { return &PolynomialFeatures{} },
+}
+
+func transformerType(t Transformer) (string, error) {
+	switch t.(type) {
+	case *StandardScaler:
+		return "standard", nil
+	case *MinMaxScaler:
+		return "min_max", nil
+	case *RobustScaler:
+		return "robust", nil
+	case *OneHotEncoder:
+		return "one_hot", nil
+	case *PolynomialFeatures:
+		return "polynomial", nil
+	}
+
+	return "", fmt.Errorf("pipeline: can not save transformer of type %T", t)
+}
+
+// NewTransformer returns an unfitted transformer by its type name, one
+// of standard, min_max, robust, one_hot or polynomial
+func NewTransformer(typ string) (Transformer, error) {
+	mk, ok := transformers[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown transformer %q", typ)
+	}
+
+	return mk(), nil
+}
+
+func (p Pipeline) MarshalJSON() ([]byte, error) {
+	steps := make([]pipelineStep, len(p))
+
+	for i, t := range p {
+		typ, err := transformerType(t)
+		if err != nil {
+			return nil, err
+		}
+
+		params, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+
+		steps[i] = pipelineStep{Type: typ, Params: params}
+	}
+
+	return json.Marshal(steps)
+}
+
+func (p *Pipeline) UnmarshalJSON(b []byte) error {
+	var steps []pipelineStep
+	if err := json.Unmarshal(b, &steps); err != nil {
+		return err
+	}
+
+	*p = make(Pipeline, len(steps))
+	for i, s := range steps {
+		t, err := NewTransformer(s.Type)
+		if err != nil {
+			return fmt.Errorf("pipeline step %d: %w", i, err)
+		}
+
+		if err := json.Unmarshal(s.Params, t); err != nil {
+			return fmt.Errorf("pipeline step %d: %w", i, err)
+		}
+
+		(*p)[i] = t
+	}
+
+	return nil
+}
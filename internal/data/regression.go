@@ -0,0 +1,62 @@
+package data
+
+import (
+	"math"
+	"math/rand"
+)
+
+// MakeRegression generates a random linear regression problem. The
+// target is a weighted sum of the features, with weights drawn
+// uniformly from [0, 100) like scikit-learn, plus Gaussian noise.
+// nSamples: number of points
+// nFeatures: number of features, drawn from a standard normal
+// noise: standard deviation of the noise added to the target
+// seed: seed for the random number generator
+func MakeRegression(nSamples int, nFeatures int, noise float64, seed int64) ([][]float64, []float64) {
+	rng := rand.New(rand.NewSource(seed))
+
+	coef := make([]float64, nFeatures)
+	for j := range coef {
+		coef[j] = 100 * rng.Float64()
+	}
+
+	X := make([][]float64, nSamples)
+	y := make([]float64, nSamples)
+
+	for i := range nSamples {
+		X[i] = make([]float64, nFeatures)
+		for j := range X[i] {
+			X[i][j] = randNorm(rng)
+			y[i] += coef[j] * X[i][j]
+		}
+		y[i] += noise * randNorm(rng)
+	}
+
+	return X, y
+}
+
+// MakeSwissRoll generates points on a rolled up 2D sheet in 3D. The
+// target is each point's position along the roll, which makes it a
+// regression or manifold learning problem.
+// nSamples: number of points
+// noise: standard deviation of Gaussian noise (set to 0.0 for no noise)
+// seed: seed for the random number generator
+func MakeSwissRoll(nSamples int, noise float64, seed int64) ([][]float64, []float64) {
+	rng := rand.New(rand.NewSource(seed))
+
+	X := make([][]float64, nSamples)
+	t := make([]float64, nSamples)
+
+	for i := range nSamples {
+		t[i] = 1.5 * math.Pi * (1 + 2*rng.Float64())
+		height := 21 * rng.Float64()
+
+		X[i] = []float64{
+			t[i]*math.Cos(t[i]) + noise*randNorm(rng),
+			height + noise*randNorm(rng),
+			t[i]*math.Sin(t[i]) + noise*randNorm(rng),
+		}
+	}
+
+	return X, t
+}
@@ -0,0 +1,47 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// MakeSpirals generates interleaved spiral arms, one class per arm, as
+// in the CS231n neural network case study.
+// nSamples: total number of points, spread evenly between the arms
+// nClasses: number of arms
+// noise: standard deviation of Gaussian noise added to the angle
+// seed: seed for the random number generator
+func MakeSpirals(nSamples int, nClasses int, noise float64, seed int64) ([][]float64, []int, error) {
+	if nClasses < 1 {
+		return nil, nil, fmt.Errorf("make spirals: need at least one class, got %d", nClasses)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+
+	X := make([][]float64, 0, nSamples)
+	y := make([]int, 0, nSamples)
+
+	for class := range nClasses {
+		n := nSamples / nClasses
+		if class < nSamples%nClasses {
+			n++
+		}
+
+		for i := range n {
+			frac := 0.0
+			if n > 1 {
+				frac = float64(i) / float64(n-1)
+			}
+
+			r := frac
+			theta := 4*float64(class) + 4*frac + noise*randNorm(rng)
+			X = append(X, []float64{r * math.Sin(theta), r * math.Cos(theta)})
+			y = append(y, class)
+		}
+	}
+
+	shuffleXY(rng, X, y)
+
+	return X, y, nil
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// groups returns the shuffled indices of y, per class if stratify is set
+func groups(y []int, stratify bool, rng *rand.Rand) [][]int {
+	var gs [][]int
+
+	if stratify {
+		byClass := make(map[int][]int)
+		var classes []int
+		for i, yi := range y {
+			if _, ok := byClass[yi]; !ok {
+				classes = append(classes, yi)
+			}
+			byClass[yi] = append(byClass[yi], i)
+		}
+		slices.Sort(classes)
+
+		for _, c := range classes {
+			gs = append(gs, byClass[c])
+		}
+	} else {
+		all := make([]int, len(y))
+		for i := range all {
+			all[i] = i
+		}
+		gs = append(gs, all)
+	}
+
+	for _, g := range gs {
+		rng.Shuffle(len(g), func(i, j int) {
+			g[i], g[j] = g[j], g[i]
+		})
+	}
+
+	return gs
+}
+
+// Split divides the indices of y into len(fracs)+1 shuffled parts. Part
+// i+1 gets fracs[i] of the samples and the first part gets the rest. If
+// stratify is set then each class is split separately so the parts
+// have the same class balance as y, otherwise only the length of y is
+// used.
+func Split(y []int, stratify bool, seed int64, fracs ...float64) ([][]int, error) {
+	total := 0.0
+	for _, f := range fracs {
+		if f < 0 {
+			return nil, fmt.Errorf("split: negative fraction %v", f)
+		}
+		total += f
+	}
+	if total > 1 {
+		return nil, fmt.Errorf("split: fractions sum to %v which is more than 1", total)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	parts := make([][]int, len(fracs)+1)
+
+	for _, g := range groups(y, stratify, rng) {
+		start := 0
+		for i, f := range fracs {
+			n := int(math.Round(f * float64(len(g))))
+			n = min(n, len(g)-start)
+			parts[i+1] = append(parts[i+1], g[start:start+n]...)
+			start += n
+		}
+		parts[0] = append(parts[0], g[start:]...)
+	}
+
+	for _, p := range parts {
+		rng.Shuffle(len(p), func(i, j int) {
+			p[i], p[j] = p[j], p[i]
+		})
+	}
+
+	return parts, nil
+}
+
+// TrainTestSplit is Split with a single test fraction
+func TrainTestSplit(y []int, testFrac float64, stratify bool, seed int64) ([]int, []int, error) {
+	parts, err := Split(y, stratify, seed, testFrac)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// TrainValTestSplit is Split with validation and test fractions
+func TrainValTestSplit(y []int, valFrac float64, testFrac float64, stratify bool, seed int64) ([]int, []int, []int, error) {
+	parts, err := Split(y, stratify, seed, valFrac, testFrac)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return parts[0], parts[1], parts[2], nil
+}
+
+// Fold is one round of cross-validation
+type Fold struct {
+	Train []int
+	Test  []int
+}
+
+// KFold divides the indices of y into k folds of nearly equal size. Each
+// fold is the test set once. With stratify set each fold has about the
+// same class balance as y.
+func KFold(y []int, k int, stratify bool, seed int64) ([]Fold, error) {
+	if k < 2 || k > len(y) {
+		return nil, fmt.Errorf("k-fold: k = %d with %d samples", k, len(y))
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	tests := make([][]int, k)
+
+	// Deal the samples out like cards, continuing from the fold after
+	// the last one dealt to so that small classes don't all go first
+	next := 0
+	for _, g := range groups(y, stratify, rng) {
+		for _, i := range g {
+			tests[next] = append(tests[next], i)
+			next = (next + 1) % k
+		}
+	}
+
+	folds := make([]Fold, k)
+	for f := range folds {
+		folds[f].Test = tests[f]
+		for o, t := range tests {
+			if o != f {
+				folds[f].Train = append(folds[f].Train, t...)
+			}
+		}
+	}
+
+	return folds, nil
+}
+
+// Take selects the rows of X and y at idx
+func Take[Y any](X [][]float64, y []Y, idx []int) ([][]float64, []Y) {
+	Xs := make([][]float64, len(idx))
+	ys := make([]Y, len(idx))
+
+	for i, j := range idx {
+		Xs[i] = X[j]
+		ys[i] = y[j]
+	}
+
+	return Xs, ys
+}
@@ -0,0 +1,31 @@
+package data
+
+import (
+	"math/rand"
+)
+
+// MakeXOR generates points uniformly in the square [-1, 1]^2 labelled 1
+// when exactly one coordinate is positive. Noise is added after
+// labelling so that it blurs the boundary.
+// nSamples: total number of points
+// noise: standard deviation of Gaussian noise (set to 0.0 for no noise)
+// seed: seed for the random number generator
+func MakeXOR(nSamples int, noise float64, seed int64) ([][]float64, []int) {
+	rng := rand.New(rand.NewSource(seed))
+
+	X := make([][]float64, nSamples)
+	y := make([]int, nSamples)
+
+	for i := range nSamples {
+		a := 2*rng.Float64() - 1
+		b := 2*rng.Float64() - 1
+
+		if (a > 0) != (b > 0) {
+			y[i] = 1
+		}
+
+		X[i] = []float64{a + noise*randNorm(rng), b + noise*randNorm(rng)}
+	}
+
+	return X, y
+}
@@ -0,0 +1,88 @@
+package grad
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Activation applies an activation function to a neuron's weighted sum.
+// It should build the result out of Value operations so that it can be
+// backpropagated through.
+type Activation[T constraints.Float] func(x *Value[T], withArgs ...ValueArg[T]) *Value[T]
+
+// RegisterActFn makes a custom activation function available under name
+// so that it can be given to SetActFn or the MLP constructor options
+func (c *Context[T]) RegisterActFn(name ActFn, fn Activation[T]) error {
+	if name == "" {
+		return errors.New("register activation: empty name")
+	}
+	if fn == nil {
+		return fmt.Errorf("register activation %s: nil function", name)
+	}
+	if isBuiltinActFn(name) {
+		return fmt.Errorf("register activation %s: conflicts with builtin", name)
+	}
+
+	if c.actFns == nil {
+		c.actFns = make(map[ActFn]Activation[T])
+	}
+	c.actFns[name] = fn
+
+	return nil
+}
+
+func isBuiltinActFn(fn ActFn) bool {
+	switch fn {
+	case LinearActFn, TanhActFn, ReluActFn:
+		return true
+	}
+
+	return false
+}
+
+func (c *Context[T]) checkActFn(fn ActFn) error {
+	if isBuiltinActFn(fn) {
+		return nil
+	}
+	if _, ok := c.actFns[fn]; ok {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrUnknownActFn, fn)
+}
+
+type MLPArg[T constraints.Float] func(args *MLPArgs[T])
+
+type MLPArgs[T constraints.Float] struct {
+	actFns       []ActFn
+	neuronActFns map[[2]int]ActFn
+	dropout      T
+}
+
+// WithActFns sets the activation function of each layer, there must be
+// one per layer
+func (c *Context[T]) WithActFns(fns ...ActFn) MLPArg[T] {
+	return func(args *MLPArgs[T]) {
+		args.actFns = fns
+	}
+}
+
+// WithNeuronActFn overrides the activation of a single neuron. It is
+// applied after WithActFns.
+func (c *Context[T]) WithNeuronActFn(layer int, neuron int, fn ActFn) MLPArg[T] {
+	return func(args *MLPArgs[T]) {
+		if args.neuronActFns == nil {
+			args.neuronActFns = make(map[[2]int]ActFn)
+		}
+		args.neuronActFns[[2]int{layer, neuron}] = fn
+	}
+}
+
+// WithDropout adds dropout with probability p after each hidden layer
+func (c *Context[T]) WithDropout(p T) MLPArg[T] {
+	return func(args *MLPArgs[T]) {
+		args.dropout = p
+	}
+}
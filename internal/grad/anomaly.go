@@ -0,0 +1,180 @@
+package grad
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+)
+
+// NodeRef identifies a Value without its type parameter
+type NodeRef struct {
+	ID    uint64
+	Op    Op
+	Label string
+}
+
+func (r NodeRef) String() string {
+	return fmt.Sprintf("%d (op %q, label %q)", r.ID, r.Op, r.Label)
+}
+
+func refOf[T constraints.Float](v *Value[T]) NodeRef {
+	return NodeRef{ID: v.id, Op: v.op, Label: v.label}
+}
+
+// AnomalyError reports a NaN or infinity. In the forward phase Node is
+// the first value to become non-finite while its inputs were finite. In
+// the backward phase Node is the value whose backward step produced a
+// non-finite gradient in one of its inputs. Path runs from the root
+// passed to Backward down to Node, it is empty if the anomaly was found
+// when the value was created.
+type AnomalyError struct {
+	Phase string
+	Node  NodeRef
+	Value float64
+	Path  []NodeRef
+}
+
+func (e *AnomalyError) Error() string {
+	var b strings.Builder
+
+	what := "data"
+	if e.Phase == "backward" {
+		what = "grad"
+	}
+
+	fmt.Fprintf(&b, "%s in %s: node %s produced %s %v", ErrAnomaly, e.Phase, e.Node, what, e.Value)
+
+	if len(e.Path) > 0 {
+		b.WriteString("; path from root:")
+		for i, r := range e.Path {
+			if i > 0 {
+				b.WriteString(" ->")
+			}
+			fmt.Fprintf(&b, " %s", r)
+		}
+	}
+
+	return b.String()
+}
+
+func (e *AnomalyError) Unwrap() error {
+	return ErrAnomaly
+}
+
+// SetDetectAnomaly turns on checking of every new value and, during
+// Backward, every gradient for NaN and infinity. This is slow so it is
+// meant for debugging. Turning it on or off clears any anomaly already
+// recorded.
+func (c *Context[T]) SetDetectAnomaly(on bool) {
+	c.detectAnomaly = on
+	c.anomaly = nil
+}
+
+// Anomaly returns the first non-finite value created since anomaly
+// detection was turned on, or nil
+func (c *Context[T]) Anomaly() error {
+	return c.anomaly
+}
+
+func isFinite[T constraints.Float](x T) bool {
+	return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+}
+
+// isOrigin is true if v is non-finite but its inputs are not, so it is
+// where the problem started
+func isOrigin[T constraints.Float](v *Value[T]) bool {
+	if isFinite(v.data) {
+		return false
+	}
+
+	for _, p := range v.prev {
+		if !isFinite(p.data) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Context[T]) checkForward(v *Value[T]) {
+	if c.anomaly != nil || !isOrigin(v) {
+		return
+	}
+
+	c.anomaly = &AnomalyError{
+		Phase: "forward",
+		Node:  refOf(v),
+		Value: float64(v.data),
+	}
+}
+
+// findForwardAnomaly looks for the origin of any non-finite data in the
+// graph, starting from the leaves
+func (c *Context[T]) findForwardAnomaly(root *Value[T]) error {
+	for i := len(c.topoSorted) - 1; i >= 0; i-- {
+		v := c.topoSorted[i]
+
+		if isOrigin(v) {
+			return &AnomalyError{
+				Phase: "forward",
+				Node:  refOf(v),
+				Value: float64(v.data),
+				Path:  pathTo(root, v),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Context[T]) checkBackward(root *Value[T], v *Value[T]) error {
+	for _, p := range v.prev {
+		if !isFinite(p.grad) {
+			return &AnomalyError{
+				Phase: "backward",
+				Node:  refOf(v),
+				Value: float64(p.grad),
+				Path:  pathTo(root, v),
+			}
+		}
+	}
+
+	return nil
+}
+
+// pathTo finds the shortest path from root to target
+func pathTo[T constraints.Float](root *Value[T], target *Value[T]) []NodeRef {
+	parents := map[uint64]*Value[T]{root.id: nil}
+	queue := []*Value[T]{root}
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		if v == target {
+			break
+		}
+
+		for _, p := range v.prev {
+			if _, ok := parents[p.id]; !ok {
+				parents[p.id] = v
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	if _, ok := parents[target.id]; !ok {
+		return nil
+	}
+
+	var path []NodeRef
+	for v := target; v != nil; v = parents[v.id] {
+		path = append(path, refOf(v))
+	}
+	slices.Reverse(path)
+
+	return path
+}
@@ -0,0 +1,49 @@
+package grad
+
+import (
+	"iter"
+	"math"
+
+	"golang.org/x/exp/constraints"
+)
+
+// GradNorm is the L2 norm of all the params' gradients taken together
+func GradNorm[T constraints.Float](params iter.Seq[*Value[T]]) T {
+	var sum float64
+	for p := range params {
+		sum += float64(p.grad * p.grad)
+	}
+
+	return T(math.Sqrt(sum))
+}
+
+// ClipGradValue clamps each gradient to [-clip, clip]. It returns the
+// global norm from before clipping.
+func ClipGradValue[T constraints.Float](params iter.Seq[*Value[T]], clip T) T {
+	norm := GradNorm(params)
+
+	for p := range params {
+		p.grad = max(-clip, min(clip, p.grad))
+	}
+
+	return norm
+}
+
+// ClipGradNorm scales the gradients so that their global norm is at
+// most maxNorm. It returns the norm from before clipping. If the norm is
+// NaN or infinite then the gradients are left alone, because scaling
+// them would only spread the problem.
+func ClipGradNorm[T constraints.Float](params iter.Seq[*Value[T]], maxNorm T) T {
+	norm := GradNorm(params)
+
+	if norm <= maxNorm || math.IsNaN(float64(norm)) || math.IsInf(float64(norm), 0) {
+		return norm
+	}
+
+	scale := maxNorm / norm
+	for p := range params {
+		p.grad *= scale
+	}
+
+	return norm
+}
@@ -0,0 +1,87 @@
+package grad
+
+import (
+	"fmt"
+	"iter"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Trainable is implemented by modules which behave differently during
+// training and evaluation. Modules which don't implement it behave the
+// same in both. Training reports the current mode, modules start in
+// training mode.
+type Trainable interface {
+	SetTraining(training bool)
+	Training() bool
+}
+
+var (
+	_ Trainable = (*MLP[float64])(nil)
+	_ Trainable = (*Sequential[float64])(nil)
+	_ Trainable = (*Dropout[float64])(nil)
+)
+
+// Dropout zeros each input with probability p during training and
+// scales the rest by 1/(1-p). In evaluation mode it passes the inputs
+// through unchanged.
+type Dropout[T constraints.Float] struct {
+	ctx      *Context[T]
+	p        T
+	training bool
+}
+
+var _ Module[float64] = (*Dropout[float64])(nil)
+
+func (c *Context[T]) Dropout(p T) (*Dropout[T], error) {
+	if p < 0 || p >= 1 {
+		return nil, fmt.Errorf("dropout: probability %v not in [0, 1)", p)
+	}
+
+	return &Dropout[T]{
+		ctx:      c,
+		p:        p,
+		training: true,
+	}, nil
+}
+
+func (d *Dropout[T]) SetTraining(training bool) {
+	d.training = training
+}
+
+func (d *Dropout[T]) Training() bool {
+	return d.training
+}
+
+// Forward multiplies each input by a constant mask value, so the
+// gradient of a dropped unit is zero and kept units are scaled the same
+// way as in the forward pass
+func (d *Dropout[T]) Forward(inputs []*Value[T]) []*Value[T] {
+	if !d.training || d.p == 0 {
+		return inputs
+	}
+
+	c := d.ctx
+	rng := c.Rand()
+	scale := 1 / (1 - d.p)
+	outs := make([]*Value[T], len(inputs))
+
+	for i, x := range inputs {
+		var m T
+		if T(rng.Float64()) >= d.p {
+			m = scale
+		}
+
+		outs[i] = x.Mul(c.Val(m, c.WithLabel("mask")), c.WithLabel("dropout"))
+	}
+
+	return outs
+}
+
+func (d *Dropout[T]) Parameters() iter.Seq[*Value[T]] {
+	return func(yield func(*Value[T]) bool) {}
+}
+
+func (d *Dropout[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return func(yield func(string, *Value[T]) bool) {}
+}
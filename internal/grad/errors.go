@@ -0,0 +1,44 @@
+package grad
+
+import (
+	"errors"
+
+	"golang.org/x/exp/constraints"
+)
+
+var (
+	ErrInputSize      = errors.New("input size mismatch")
+	ErrEmptySum       = errors.New("sum of no values")
+	ErrUnknownOp      = errors.New("unknown op")
+	ErrMalformedValue = errors.New("malformed value")
+	ErrUnknownActFn   = errors.New("unknown activation function")
+	ErrAnomaly        = errors.New("anomaly detected")
+	ErrUnknownOptim   = errors.New("unknown optimizer")
+	ErrBadLabel       = errors.New("label out of range")
+)
+
+// CheckedModule is implemented by modules which can report bad inputs
+// as an error instead of panicking
+type CheckedModule[T constraints.Float] interface {
+	Module[T]
+	ForwardE(inputs []*Value[T]) ([]*Value[T], error)
+}
+
+var (
+	_ CheckedModule[float64] = (*Layer[float64])(nil)
+	_ CheckedModule[float64] = (*MLP[float64])(nil)
+	_ CheckedModule[float64] = (*Sequential[float64])(nil)
+	_ CheckedModule[float64] = (*LayerNorm[float64])(nil)
+	_ CheckedModule[float64] = (*BatchNorm[float64])(nil)
+	_ CheckedModule[float64] = Softmax[float64]{}
+)
+
+// ForwardE calls m.ForwardE if m is a CheckedModule, otherwise it calls
+// m.Forward
+func ForwardE[T constraints.Float](m Module[T], inputs []*Value[T]) ([]*Value[T], error) {
+	if cm, ok := m.(CheckedModule[T]); ok {
+		return cm.ForwardE(inputs)
+	}
+
+	return m.Forward(inputs), nil
+}
@@ -3,6 +3,7 @@ package grad
 import (
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"sync/atomic"
 
 	"golang.org/x/exp/constraints"
@@ -12,14 +13,17 @@ import (
 type Op string
 
 const (
-	OpNil  Op = ""
-	OpAdd  Op = "+"
-	OpMul  Op = "*"
-	OpTanh Op = "tanh"
-	OpRelu Op = "relu"
-	OpExp  Op = "exp"
-	OpPow  Op = "pow"
-	OpDiv  Op = "/"
+	OpNil    Op = ""
+	OpAdd    Op = "+"
+	OpMul    Op = "*"
+	OpTanh   Op = "tanh"
+	OpRelu   Op = "relu"
+	OpExp    Op = "exp"
+	OpPow    Op = "pow"
+	OpDiv    Op = "/"
+	OpLog    Op = "log"
+	OpSumSq  Op = "sumsq"
+	OpSumAbs Op = "sumabs"
 )
 
 type Value[T constraints.Float] struct {
@@ -43,9 +47,37 @@ type ValueArgs[T constraints.Float] struct {
 	param    T
 }
 
+// Context creates Values and holds the state shared by a graph, such as
+// the random number generator. It is not safe for concurrent use, a
+// Context and its Values must only be used by one goroutine at a time.
+// Give each goroutine its own Context to train models in parallel.
 type Context[T constraints.Float] struct {
 	maxId atomic.Uint64
 	topoSorted []*Value[T]
+	actFns map[ActFn]Activation[T]
+	rng *rand.Rand
+	detectAnomaly bool
+	anomaly error
+	// checkingGrad is set by CheckGrad to stop modules updating their
+	// running statistics
+	checkingGrad bool
+}
+
+// Seed sets the random number generator used for initialising
+// parameters and dropout masks so that results are reproducible
+func (c *Context[T]) Seed(seed uint64) {
+	c.rng = rand.New(rand.NewPCG(seed, 0))
+}
+
+// Rand returns the Context's random number generator, if Seed has not
+// been called then it is randomly seeded. Like the rest of the Context
+// it must not be used concurrently.
+func (c *Context[T]) Rand() *rand.Rand {
+	if c.rng == nil {
+		c.Seed(rand.Uint64())
+	}
+
+	return c.rng
 }
 
 func (c *Context[T]) WithPrev(children ...*Value[T]) ValueArg[T] {
@@ -85,7 +117,7 @@ func (c *Context[T]) Val(d T, withArgs ...ValueArg[T]) *Value[T] {
 		fn(&args)
 	}
 
-	return &Value[T]{
+	v := &Value[T]{
 		ctx:      c,
 		data:     d,
 		grad:     args.grad,
@@ -95,6 +127,12 @@ func (c *Context[T]) Val(d T, withArgs ...ValueArg[T]) *Value[T] {
 		id:       c.maxId.Add(1),
 		param:    args.param,
 	}
+
+	if c.detectAnomaly {
+		c.checkForward(v)
+	}
+
+	return v
 }
 
 func (c *Context[T]) Vals(ds ...T) []*Value[T] {
@@ -129,11 +167,30 @@ func (c *Context[T]) topoSort(root *Value[T]) []*Value[T] {
 	return topoSorted 
 }
 
-func (c *Context[T]) Backward(root *Value[T]) {
+// Backward calculates the gradient of root with respect to every value
+// it depends on. It returns an error if the graph contains an unknown op
+// or a value with the wrong number of children, which is checked before
+// any gradient is changed, or if anomaly detection finds a NaN or
+// infinity, see SetDetectAnomaly. Anomaly detection stops the pass at
+// the value where the anomaly appeared, so the gradients are left
+// partially accumulated.
+func (c *Context[T]) Backward(root *Value[T]) error {
 	if len(c.topoSorted) < 1 || c.topoSorted[0].id != root.id {
 		c.topoSorted = c.topoSort(root)
 	}
 
+	if c.detectAnomaly {
+		if err := c.findForwardAnomaly(root); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range c.topoSorted {
+		if err := v.check(); err != nil {
+			return err
+		}
+	}
+
 	for _, v := range c.topoSorted {
 		v.grad = 0
 	}
@@ -142,28 +199,102 @@ func (c *Context[T]) Backward(root *Value[T]) {
 
 	for _, v := range c.topoSorted {
 		v.backward()
+
+		if c.detectAnomaly {
+			if err := c.checkBackward(root, v); err != nil {
+				return err
+			}
+		}
 	}
+
+	return nil
 }
 
+// Sum panics if vs is empty, see SumE
 func (c *Context[T]) Sum(vs []*Value[T]) *Value[T] {
+	sum, err := c.SumE(vs)
+	if err != nil {
+		panic(err)
+	}
+
+	return sum
+}
+
+func (c *Context[T]) SumE(vs []*Value[T]) (*Value[T], error) {
+	if len(vs) < 1 {
+		return nil, ErrEmptySum
+	}
+
 	sum := vs[0]
 	for _, l := range vs[1:] {
 		sum = sum.Add(l)
 	}
 
-	return sum
+	return sum, nil
+}
+
+// SumSq is the sum of the squares of vs as a single node, which keeps
+// regularization terms over many parameters small
+func (c *Context[T]) SumSq(vs []*Value[T], withArgs ...ValueArg[T]) *Value[T] {
+	var sum T
+	for _, v := range vs {
+		sum += v.data * v.data
+	}
+
+	args := []ValueArg[T]{c.WithPrev(vs...), c.WithOp(OpSumSq)}
+	args = append(args, withArgs...)
+
+	return c.Val(sum, args...)
+}
+
+// SumAbs is the sum of the absolute values of vs as a single node
+func (c *Context[T]) SumAbs(vs []*Value[T], withArgs ...ValueArg[T]) *Value[T] {
+	var sum T
+	for _, v := range vs {
+		sum += T(math.Abs(float64(v.data)))
+	}
+
+	args := []ValueArg[T]{c.WithPrev(vs...), c.WithOp(OpSumAbs)}
+	args = append(args, withArgs...)
+
+	return c.Val(sum, args...)
 }
 
 func (v Value[T]) String() string {
 	return fmt.Sprintf("Value(data=%v, grad=%v)", v.data, v.grad)
 }
 
+// arity is the number of children each op must have, -1 means any
+var arity = map[Op]int{
+	OpNil:    0,
+	OpAdd:    -1,
+	OpMul:    2,
+	OpPow:    1,
+	OpTanh:   1,
+	OpRelu:   1,
+	OpExp:    1,
+	OpLog:    1,
+	OpSumSq:  -1,
+	OpSumAbs: -1,
+}
+
+// check returns an error if backward can't handle v
+func (v *Value[T]) check() error {
+	n, ok := arity[v.op]
+	if !ok {
+		return fmt.Errorf("%w: %q at node %d", ErrUnknownOp, v.op, v.id)
+	}
+	if n >= 0 && len(v.prev) != n {
+		return fmt.Errorf("%w: node %d with op %q has %d children, want %d", ErrMalformedValue, v.id, v.op, len(v.prev), n)
+	}
+
+	return nil
+}
+
+// backward adds v's contribution to the gradients of its children, v
+// must have passed check
 func (v *Value[T]) backward() {
 	switch v.op {
-	case OpNil:
-		if len(v.prev) > 0 {
-			panic("Constant has children")
-		}
 	case OpAdd:
 		for _, c := range v.prev {
 			c.grad += v.grad
@@ -192,6 +323,22 @@ func (v *Value[T]) backward() {
 		a := v.prev[0]
 
 		a.grad += v.data * v.grad	
+	case OpLog:
+		a := v.prev[0]
+
+		a.grad += v.grad / a.data
+	case OpSumSq:
+		for _, c := range v.prev {
+			c.grad += 2 * c.data * v.grad
+		}
+	case OpSumAbs:
+		for _, c := range v.prev {
+			if c.data > 0 {
+				c.grad += v.grad
+			} else if c.data < 0 {
+				c.grad -= v.grad
+			}
+		}
 	}
 }
 
@@ -261,6 +408,14 @@ func (v *Value[T]) Exp(withArgs ...ValueArg[T]) *Value[T] {
 	return c.Val(T(math.Exp(float64(v.data))), args...)
 }
 
+func (v *Value[T]) Log(withArgs ...ValueArg[T]) *Value[T] {
+	c := v.ctx
+	args := []ValueArg[T]{c.WithPrev(v), c.WithOp(OpLog)}
+	args = append(args, withArgs...)
+
+	return c.Val(T(math.Log(float64(v.data))), args...)
+}
+
 func (v Value[T]) Data() T {
 	return v.data
 }
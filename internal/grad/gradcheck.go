@@ -0,0 +1,45 @@
+package grad
+
+import (
+	"fmt"
+	"math"
+)
+
+// CheckGrad compares the gradients found by Backward with central
+// finite differences and returns the largest absolute difference. f
+// must rebuild the graph from params each time it is called, because
+// the params' data is perturbed by eps between calls. Modules of c do not
+// update their running statistics, such as BatchNorm's, while checking.
+// An error from Backward is returned as there are no gradients to compare.
+func (c *Context[T]) CheckGrad(params []*Value[T], f func() *Value[T], eps T) (T, error) {
+	c.checkingGrad = true
+	defer func() { c.checkingGrad = false }()
+
+	if err := c.Backward(f()); err != nil {
+		return 0, fmt.Errorf("check grad: %w", err)
+	}
+
+	analytic := make([]T, len(params))
+	for i, p := range params {
+		analytic[i] = p.grad
+	}
+
+	var worst T
+	for i, p := range params {
+		orig := p.data
+
+		p.data = orig + eps
+		plus := f().data
+		p.data = orig - eps
+		minus := f().data
+		p.data = orig
+
+		numeric := (plus - minus) / (2 * eps)
+		diff := T(math.Abs(float64(numeric - analytic[i])))
+		if diff > worst || math.IsNaN(float64(diff)) {
+			worst = diff
+		}
+	}
+
+	return worst, nil
+}
@@ -0,0 +1,178 @@
+package grad
+
+import (
+	"fmt"
+	"iter"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Module is a component of a model which maps a vector of inputs to a
+// vector of outputs. Layer, MLP and Sequential are all modules.
+type Module[T constraints.Float] interface {
+	Forward(inputs []*Value[T]) []*Value[T]
+	Parameters() iter.Seq[*Value[T]]
+	NamedParameters() iter.Seq2[string, *Value[T]]
+}
+
+// Buffered is implemented by modules with state which is not learned by
+// gradient descent but is part of the model, such as the running
+// statistics of BatchNorm
+type Buffered[T constraints.Float] interface {
+	NamedBuffers() iter.Seq2[string, *T]
+}
+
+var (
+	_ Module[float64] = (*Layer[float64])(nil)
+	_ Module[float64] = (*MLP[float64])(nil)
+	_ Module[float64] = (*Sequential[float64])(nil)
+
+	_ Buffered[float64] = (*Sequential[float64])(nil)
+)
+
+// moduleState yields the data of a module's parameters followed by its
+// buffers, if it has any
+func moduleState[T constraints.Float](m Module[T]) iter.Seq2[string, *T] {
+	return func(yield func(string, *T) bool) {
+		for name, d := range paramData(m.NamedParameters()) {
+			if !yield(name, d) {
+				return
+			}
+		}
+
+		b, ok := m.(Buffered[T])
+		if !ok {
+			return
+		}
+		for name, d := range b.NamedBuffers() {
+			if !yield(name, d) {
+				return
+			}
+		}
+	}
+}
+
+// StateDictOf takes a snapshot of a module's parameters and buffers
+func StateDictOf[T constraints.Float](m Module[T]) StateDict[T] {
+	return stateDict(moduleState(m))
+}
+
+// LoadStateDictInto copies sd into the module's parameters and buffers,
+// see MLP.LoadStateDict
+func LoadStateDictInto[T constraints.Float](m Module[T], sd StateDict[T]) error {
+	return loadStateDict(moduleState(m), sd)
+}
+
+// Sequential feeds the outputs of each module into the next
+type Sequential[T constraints.Float] struct {
+	modules []Module[T]
+}
+
+func (c *Context[T]) Seq(modules ...Module[T]) *Sequential[T] {
+	return &Sequential[T]{
+		modules: modules,
+	}
+}
+
+func (s *Sequential[T]) Append(modules ...Module[T]) {
+	s.modules = append(s.modules, modules...)
+}
+
+func (s *Sequential[T]) Modules() []Module[T] {
+	return s.modules
+}
+
+func (s *Sequential[T]) Forward(inputs []*Value[T]) []*Value[T] {
+	out, err := s.ForwardE(inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return out
+}
+
+func (s *Sequential[T]) ForwardE(inputs []*Value[T]) ([]*Value[T], error) {
+	out := inputs
+
+	for i, m := range s.modules {
+		var err error
+		if out, err = ForwardE(m, out); err != nil {
+			return nil, fmt.Errorf("module %d: %w", i, err)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *Sequential[T]) Parameters() iter.Seq[*Value[T]] {
+	return func(yield func(*Value[T]) bool) {
+		for _, m := range s.modules {
+			for v := range m.Parameters() {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// NamedParameters prefixes each module's parameter names with its
+// index, e.g. "0.neurons.1.w0"
+func (s *Sequential[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return func(yield func(string, *Value[T]) bool) {
+		for i, m := range s.modules {
+			for name, v := range m.NamedParameters() {
+				if !yield(fmt.Sprintf("%d.%s", i, name), v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// NamedBuffers prefixes the buffers of each Buffered module with its
+// index like NamedParameters
+func (s *Sequential[T]) NamedBuffers() iter.Seq2[string, *T] {
+	return func(yield func(string, *T) bool) {
+		for i, m := range s.modules {
+			b, ok := m.(Buffered[T])
+			if !ok {
+				continue
+			}
+			for name, d := range b.NamedBuffers() {
+				if !yield(fmt.Sprintf("%d.%s", i, name), d) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Sequential[T]) StateDict() StateDict[T] {
+	return StateDictOf[T](s)
+}
+
+func (s *Sequential[T]) LoadStateDict(sd StateDict[T]) error {
+	return LoadStateDictInto[T](s, sd)
+}
+
+// SetTraining passes the mode on to any modules which are Trainable
+func (s *Sequential[T]) SetTraining(training bool) {
+	for _, m := range s.modules {
+		if t, ok := m.(Trainable); ok {
+			t.SetTraining(training)
+		}
+	}
+}
+
+// Training is the mode of the first Trainable module, or true if there
+// are none
+func (s *Sequential[T]) Training() bool {
+	for _, m := range s.modules {
+		if t, ok := m.(Trainable); ok {
+			return t.Training()
+		}
+	}
+
+	return true
+}
@@ -3,7 +3,6 @@ package grad
 import (
 	"fmt"
 	"iter"
-	"math/rand/v2"
 
 	"golang.org/x/exp/constraints"
 )
@@ -32,13 +31,27 @@ func (c *Context[T]) Neu(nin uint) *Neuron[T] {
 	// For Tanh activation and the smaller NN example a starting value of 0.5 allowed for successful training
 	// For Relu and the moon fitting demo however it would get stuck
 	for i := range n.w {
-		n.w[i] = c.Val(T(rand.NormFloat64()), c.WithLabel(fmt.Sprintf("w%d", i)))
+		n.w[i] = c.Val(T(c.Rand().NormFloat64()), c.WithLabel(fmt.Sprintf("w%d", i)))
 	}
 
 	return &n
 }
 
+// Forward panics if the inputs are the wrong size, see ForwardE
 func (n *Neuron[T]) Forward(inputs []*Value[T]) *Value[T] {
+	out, err := n.ForwardE(inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return out
+}
+
+func (n *Neuron[T]) ForwardE(inputs []*Value[T]) (*Value[T], error) {
+	if len(inputs) != len(n.w) {
+		return nil, fmt.Errorf("%w: neuron has %d weights, got %d inputs", ErrInputSize, len(n.w), len(inputs))
+	}
+
 	act := n.b
 
 	for i, xi := range inputs {
@@ -47,14 +60,18 @@ func (n *Neuron[T]) Forward(inputs []*Value[T]) *Value[T] {
 
 	switch n.actFn {
 	case LinearActFn:
-		return act
+		return act, nil
 	case TanhActFn:
-		return act.Tanh(act.ctx.WithLabel("out"))
+		return act.Tanh(act.ctx.WithLabel("out")), nil
 	case ReluActFn:
-		return act.Relu(act.ctx.WithLabel("out"))
+		return act.Relu(act.ctx.WithLabel("out")), nil
+	}
+
+	if fn, ok := act.ctx.actFns[n.actFn]; ok {
+		return fn(act, act.ctx.WithLabel("out")), nil
 	}
 
-	panic("Unhandled activation")
+	return nil, fmt.Errorf("%w: %q", ErrUnknownActFn, n.actFn)
 }
 
 func (n *Neuron[T]) Parameters() iter.Seq[*Value[T]] {
@@ -70,6 +87,21 @@ func (n *Neuron[T]) Parameters() iter.Seq[*Value[T]] {
 	}
 }
 
+// NamedParameters yields the same parameters as Parameters along with
+// their names, "b" for the bias and "w<i>" for the weights
+func (n *Neuron[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return func(yield func(string, *Value[T]) bool) {
+		if !yield("b", n.b) {
+			return
+		}
+		for i, v := range n.w {
+			if !yield(fmt.Sprintf("w%d", i), v) {
+				return
+			}
+		}
+	}
+}
+
 type Layer[T constraints.Float] struct {
 	neurons []*Neuron[T]
 }
@@ -87,13 +119,26 @@ func (c *Context[T]) Lay(nin uint, nout uint) *Layer[T] {
 }
 
 func (l *Layer[T]) Forward(inputs []*Value[T]) []*Value[T] {
+	outs, err := l.ForwardE(inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return outs
+}
+
+func (l *Layer[T]) ForwardE(inputs []*Value[T]) ([]*Value[T], error) {
 	outs := make([]*Value[T], len(l.neurons))
 
 	for i, n := range l.neurons {
-		outs[i] = n.Forward(inputs)
+		out, err := n.ForwardE(inputs)
+		if err != nil {
+			return nil, fmt.Errorf("neuron %d: %w", i, err)
+		}
+		outs[i] = out
 	}
 
-	return outs
+	return outs, nil
 }
 
 func (l *Layer[T]) Parameters() iter.Seq[*Value[T]] {
@@ -108,41 +153,161 @@ func (l *Layer[T]) Parameters() iter.Seq[*Value[T]] {
 	}
 }
 
+func (l *Layer[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return func(yield func(string, *Value[T]) bool) {
+		for i, n := range l.neurons {
+			for name, v := range n.NamedParameters() {
+				if !yield(fmt.Sprintf("neurons.%d.%s", i, name), v) {
+					return
+				}
+			}
+		}
+	}
+}
+
 type MLP[T constraints.Float] struct {
-	layers []*Layer[T]
+	layers   []*Layer[T]
+	dropouts []*Dropout[T]
 }
 
 func (c *Context[T]) MLP(nin uint, nout uint, nouts ...uint) *MLP[T] {
 	sz := []uint{nin, nout}
 	sz = append(sz, nouts...)
 
+	mlp, err := c.MLPWith(sz)
+	if err != nil {
+		panic(err)
+	}
+
+	return mlp
+}
+
+// MLPWith creates an MLP with the layer sizes in sz, the first being
+// the number of inputs. Unlike MLP it accepts options and checks them.
+func (c *Context[T]) MLPWith(sz []uint, withArgs ...MLPArg[T]) (*MLP[T], error) {
+	var args MLPArgs[T]
+
+	for _, fn := range withArgs {
+		fn(&args)
+	}
+
+	if len(sz) < 2 {
+		return nil, fmt.Errorf("MLP: need at least 2 sizes, got %d", len(sz))
+	}
+
+	depth := len(sz) - 1
+	if args.actFns != nil && len(args.actFns) != depth {
+		return nil, fmt.Errorf("MLP: %d activation functions given for %d layers", len(args.actFns), depth)
+	}
+
+	if args.dropout < 0 || args.dropout >= 1 {
+		return nil, fmt.Errorf("MLP: dropout probability %v not in [0, 1)", args.dropout)
+	}
+
+	for _, fn := range args.actFns {
+		if err := c.checkActFn(fn); err != nil {
+			return nil, fmt.Errorf("MLP: %w", err)
+		}
+	}
+
+	for at, fn := range args.neuronActFns {
+		if at[0] < 0 || at[0] >= depth || at[1] < 0 || at[1] >= int(sz[at[0]+1]) {
+			return nil, fmt.Errorf("MLP: no neuron %d in layer %d", at[1], at[0])
+		}
+		if err := c.checkActFn(fn); err != nil {
+			return nil, fmt.Errorf("MLP: layer %d neuron %d: %w", at[0], at[1], err)
+		}
+	}
+
 	mlp := MLP[T]{
-		layers: make([]*Layer[T], len(sz)-1),
+		layers: make([]*Layer[T], depth),
 	}
 
 	for i := range mlp.layers {
 		mlp.layers[i] = c.Lay(sz[i], sz[i+1])
+
+		if args.actFns != nil {
+			for _, n := range mlp.layers[i].neurons {
+				n.actFn = args.actFns[i]
+			}
+		}
+	}
+
+	for at, fn := range args.neuronActFns {
+		mlp.layers[at[0]].neurons[at[1]].actFn = fn
 	}
 
-	return &mlp
+	if args.dropout > 0 {
+		mlp.dropouts = make([]*Dropout[T], depth-1)
+		for i := range mlp.dropouts {
+			mlp.dropouts[i], _ = c.Dropout(args.dropout)
+		}
+	}
+
+	return &mlp, nil
 }
 
-func (mlp *MLP[T]) SetActFn(layer int, fn ActFn) {
-	for _, n := range mlp.layers[layer].neurons {
+func (mlp *MLP[T]) SetActFn(layer int, fn ActFn) error {
+	if layer < 0 || layer >= len(mlp.layers) {
+		return fmt.Errorf("set activation: no layer %d", layer)
+	}
+
+	l := mlp.layers[layer]
+	if len(l.neurons) > 0 {
+		if err := l.neurons[0].b.ctx.checkActFn(fn); err != nil {
+			return fmt.Errorf("set activation: %w", err)
+		}
+	}
+
+	for _, n := range l.neurons {
 		n.actFn = fn
 	}
+
+	return nil
 }
 
 func (mlp *MLP[T]) Forward(inputs []*Value[T]) []*Value[T] {
-	out := mlp.layers[0].Forward(inputs)
-
-	for _, l := range mlp.layers[1:] {
-		out = l.Forward(out)
+	out, err := mlp.ForwardE(inputs)
+	if err != nil {
+		panic(err)
 	}
 
 	return out
 }
 
+func (mlp *MLP[T]) ForwardE(inputs []*Value[T]) ([]*Value[T], error) {
+	out := inputs
+
+	for i, l := range mlp.layers {
+		if i > 0 && mlp.dropouts != nil {
+			out = mlp.dropouts[i-1].Forward(out)
+		}
+
+		var err error
+		if out, err = l.ForwardE(out); err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+
+	return out, nil
+}
+
+// SetTraining switches dropout on or off. MLPs start in training mode.
+func (mlp *MLP[T]) SetTraining(training bool) {
+	for _, d := range mlp.dropouts {
+		d.SetTraining(training)
+	}
+}
+
+// Training is the mode of the MLP's dropout, or true if it has none
+func (mlp *MLP[T]) Training() bool {
+	if len(mlp.dropouts) < 1 {
+		return true
+	}
+
+	return mlp.dropouts[0].Training()
+}
+
 func (mlp *MLP[T]) Parameters() iter.Seq[*Value[T]] {
 	return func(yield func(*Value[T]) bool) {
 		for _, l := range mlp.layers {
@@ -155,6 +320,28 @@ func (mlp *MLP[T]) Parameters() iter.Seq[*Value[T]] {
 	}
 }
 
+// NamedParameters yields every parameter with a name that is unique
+// within the MLP, e.g. "layers.1.neurons.3.w2"
+func (mlp *MLP[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return func(yield func(string, *Value[T]) bool) {
+		for i, l := range mlp.layers {
+			for name, v := range l.NamedParameters() {
+				if !yield(fmt.Sprintf("layers.%d.%s", i, name), v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (mlp *MLP[T]) StateDict() StateDict[T] {
+	return stateDict(paramData(mlp.NamedParameters()))
+}
+
+func (mlp *MLP[T]) LoadStateDict(sd StateDict[T]) error {
+	return loadStateDict(paramData(mlp.NamedParameters()), sd)
+}
+
 func (mlp *MLP[T]) Depth() int {
 	return len(mlp.layers)
 }
@@ -0,0 +1,357 @@
+package grad
+
+import (
+	"fmt"
+	"iter"
+	"math"
+
+	"golang.org/x/exp/constraints"
+)
+
+// BatchModule is implemented by modules which need to see a whole batch
+// of samples at once, such as BatchNorm
+type BatchModule[T constraints.Float] interface {
+	ForwardBatch(batch [][]*Value[T]) [][]*Value[T]
+	ForwardBatchE(batch [][]*Value[T]) ([][]*Value[T], error)
+}
+
+var (
+	_ BatchModule[float64] = (*Sequential[float64])(nil)
+	_ BatchModule[float64] = (*BatchNorm[float64])(nil)
+)
+
+// ForwardBatch passes a batch through m, calling Forward on each
+// sample unless m is a BatchModule. It panics where ForwardBatchE
+// would return an error.
+func ForwardBatch[T constraints.Float](m Module[T], batch [][]*Value[T]) [][]*Value[T] {
+	outs, err := ForwardBatchE(m, batch)
+	if err != nil {
+		panic(err)
+	}
+
+	return outs
+}
+
+// ForwardBatchE passes a batch through m, calling ForwardE on each
+// sample unless m is a BatchModule
+func ForwardBatchE[T constraints.Float](m Module[T], batch [][]*Value[T]) ([][]*Value[T], error) {
+	if bm, ok := m.(BatchModule[T]); ok {
+		return bm.ForwardBatchE(batch)
+	}
+
+	outs := make([][]*Value[T], len(batch))
+	for i, inputs := range batch {
+		var err error
+		if outs[i], err = ForwardE(m, inputs); err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+	}
+
+	return outs, nil
+}
+
+func (s *Sequential[T]) ForwardBatch(batch [][]*Value[T]) [][]*Value[T] {
+	outs, err := s.ForwardBatchE(batch)
+	if err != nil {
+		panic(err)
+	}
+
+	return outs
+}
+
+func (s *Sequential[T]) ForwardBatchE(batch [][]*Value[T]) ([][]*Value[T], error) {
+	out := batch
+
+	for i, m := range s.modules {
+		var err error
+		if out, err = ForwardBatchE(m, out); err != nil {
+			return nil, fmt.Errorf("module %d: %w", i, err)
+		}
+	}
+
+	return out, nil
+}
+
+func normParams[T constraints.Float](c *Context[T], nfeat uint) ([]*Value[T], []*Value[T]) {
+	gamma := make([]*Value[T], nfeat)
+	beta := make([]*Value[T], nfeat)
+
+	for i := range gamma {
+		gamma[i] = c.Val(1, c.WithLabel(fmt.Sprintf("gamma%d", i)))
+		beta[i] = c.Val(0, c.WithLabel(fmt.Sprintf("beta%d", i)))
+	}
+
+	return gamma, beta
+}
+
+func normParameters[T constraints.Float](gamma []*Value[T], beta []*Value[T]) iter.Seq2[string, *Value[T]] {
+	return func(yield func(string, *Value[T]) bool) {
+		for i, g := range gamma {
+			if !yield(fmt.Sprintf("gamma.%d", i), g) {
+				return
+			}
+		}
+		for i, b := range beta {
+			if !yield(fmt.Sprintf("beta.%d", i), b) {
+				return
+			}
+		}
+	}
+}
+
+func values[T constraints.Float](named iter.Seq2[string, *Value[T]]) iter.Seq[*Value[T]] {
+	return func(yield func(*Value[T]) bool) {
+		for _, v := range named {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// LayerNorm normalises each sample to zero mean and unit variance
+// across its features then applies a learned scale (gamma) and shift
+// (beta) per feature
+type LayerNorm[T constraints.Float] struct {
+	ctx   *Context[T]
+	gamma []*Value[T]
+	beta  []*Value[T]
+	eps   T
+}
+
+var _ Module[float64] = (*LayerNorm[float64])(nil)
+
+func (c *Context[T]) LayerNorm(nfeat uint) *LayerNorm[T] {
+	gamma, beta := normParams(c, nfeat)
+
+	return &LayerNorm[T]{
+		ctx:   c,
+		gamma: gamma,
+		beta:  beta,
+		eps:   1e-5,
+	}
+}
+
+func (ln *LayerNorm[T]) Forward(inputs []*Value[T]) []*Value[T] {
+	outs, err := ln.ForwardE(inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return outs
+}
+
+func (ln *LayerNorm[T]) ForwardE(inputs []*Value[T]) ([]*Value[T], error) {
+	if len(inputs) != len(ln.gamma) {
+		return nil, fmt.Errorf("%w: layer norm has %d features, got %d inputs", ErrInputSize, len(ln.gamma), len(inputs))
+	}
+
+	c := ln.ctx
+	invN := c.Val(1 / T(len(inputs)))
+
+	mean := c.Sum(inputs).Mul(invN, c.WithLabel("mean"))
+	negMean := mean.Mul(c.Val(-1))
+
+	diffs := make([]*Value[T], len(inputs))
+	squares := make([]*Value[T], len(inputs))
+	for i, x := range inputs {
+		diffs[i] = x.Add(negMean)
+		squares[i] = diffs[i].Mul(diffs[i])
+	}
+
+	variance := c.Sum(squares).Mul(invN, c.WithLabel("var"))
+	invStd := variance.Add(c.Val(ln.eps)).Pow(-0.5)
+
+	outs := make([]*Value[T], len(inputs))
+	for i, d := range diffs {
+		outs[i] = d.Mul(invStd).Mul(ln.gamma[i]).Add(ln.beta[i], c.WithLabel("norm"))
+	}
+
+	return outs, nil
+}
+
+func (ln *LayerNorm[T]) Parameters() iter.Seq[*Value[T]] {
+	return values(ln.NamedParameters())
+}
+
+func (ln *LayerNorm[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return normParameters(ln.gamma, ln.beta)
+}
+
+// BatchNorm normalises each feature to zero mean and unit variance
+// across a batch then applies a learned scale (gamma) and shift (beta).
+// In training mode ForwardBatch uses the batch statistics and updates
+// the running statistics, in evaluation mode it uses the running
+// statistics. Forward only sees one sample so it always uses the
+// running statistics, as do batches of one. The running statistics are
+// buffers so they are saved in a StateDict.
+type BatchNorm[T constraints.Float] struct {
+	ctx         *Context[T]
+	gamma       []*Value[T]
+	beta        []*Value[T]
+	runningMean []T
+	runningVar  []T
+	momentum    T
+	eps         T
+	training    bool
+}
+
+var (
+	_ Module[float64]   = (*BatchNorm[float64])(nil)
+	_ Trainable         = (*BatchNorm[float64])(nil)
+	_ Buffered[float64] = (*BatchNorm[float64])(nil)
+)
+
+func (c *Context[T]) BatchNorm(nfeat uint) *BatchNorm[T] {
+	gamma, beta := normParams(c, nfeat)
+	runningVar := make([]T, nfeat)
+	for i := range runningVar {
+		runningVar[i] = 1
+	}
+
+	return &BatchNorm[T]{
+		ctx:         c,
+		gamma:       gamma,
+		beta:        beta,
+		runningMean: make([]T, nfeat),
+		runningVar:  runningVar,
+		momentum:    0.1,
+		eps:         1e-5,
+		training:    true,
+	}
+}
+
+func (bn *BatchNorm[T]) SetTraining(training bool) {
+	bn.training = training
+}
+
+func (bn *BatchNorm[T]) Training() bool {
+	return bn.training
+}
+
+func (bn *BatchNorm[T]) RunningMean() []T {
+	return bn.runningMean
+}
+
+func (bn *BatchNorm[T]) RunningVar() []T {
+	return bn.runningVar
+}
+
+func (bn *BatchNorm[T]) Forward(inputs []*Value[T]) []*Value[T] {
+	outs, err := bn.ForwardE(inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return outs
+}
+
+func (bn *BatchNorm[T]) ForwardE(inputs []*Value[T]) ([]*Value[T], error) {
+	if len(inputs) != len(bn.gamma) {
+		return nil, fmt.Errorf("%w: batch norm has %d features, got %d inputs", ErrInputSize, len(bn.gamma), len(inputs))
+	}
+
+	c := bn.ctx
+	outs := make([]*Value[T], len(inputs))
+
+	for j, x := range inputs {
+		invStd := 1 / T(math.Sqrt(float64(bn.runningVar[j]+bn.eps)))
+		norm := x.Add(c.Val(-bn.runningMean[j])).Mul(c.Val(invStd))
+		outs[j] = norm.Mul(bn.gamma[j]).Add(bn.beta[j], c.WithLabel("norm"))
+	}
+
+	return outs, nil
+}
+
+func (bn *BatchNorm[T]) ForwardBatch(batch [][]*Value[T]) [][]*Value[T] {
+	outs, err := bn.ForwardBatchE(batch)
+	if err != nil {
+		panic(err)
+	}
+
+	return outs
+}
+
+func (bn *BatchNorm[T]) ForwardBatchE(batch [][]*Value[T]) ([][]*Value[T], error) {
+	for i, inputs := range batch {
+		if len(inputs) != len(bn.gamma) {
+			return nil, fmt.Errorf("%w: sample %d: batch norm has %d features, got %d inputs", ErrInputSize, i, len(bn.gamma), len(inputs))
+		}
+	}
+
+	if !bn.training || len(batch) < 2 {
+		outs := make([][]*Value[T], len(batch))
+		for i, inputs := range batch {
+			outs[i] = bn.Forward(inputs)
+		}
+
+		return outs, nil
+	}
+
+	c := bn.ctx
+	m := T(len(batch))
+	invM := c.Val(1 / m)
+	outs := make([][]*Value[T], len(batch))
+	for i := range outs {
+		outs[i] = make([]*Value[T], len(bn.gamma))
+	}
+
+	col := make([]*Value[T], len(batch))
+	diffs := make([]*Value[T], len(batch))
+	squares := make([]*Value[T], len(batch))
+	for j := range bn.gamma {
+		for i, inputs := range batch {
+			col[i] = inputs[j]
+		}
+
+		mean := c.Sum(col).Mul(invM, c.WithLabel("mean"))
+		negMean := mean.Mul(c.Val(-1))
+		for i, x := range col {
+			diffs[i] = x.Add(negMean)
+			squares[i] = diffs[i].Mul(diffs[i])
+		}
+
+		variance := c.Sum(squares).Mul(invM, c.WithLabel("var"))
+		invStd := variance.Add(c.Val(bn.eps)).Pow(-0.5)
+
+		for i, d := range diffs {
+			outs[i][j] = d.Mul(invStd).Mul(bn.gamma[j]).Add(bn.beta[j], c.WithLabel("norm"))
+		}
+
+		// CheckGrad evaluates the batch many times, which would skew the
+		// statistics
+		if c.checkingGrad {
+			continue
+		}
+
+		// The running variance is unbiased like PyTorch
+		bn.runningMean[j] = (1-bn.momentum)*bn.runningMean[j] + bn.momentum*mean.data
+		bn.runningVar[j] = (1-bn.momentum)*bn.runningVar[j] + bn.momentum*variance.data*m/(m-1)
+	}
+
+	return outs, nil
+}
+
+func (bn *BatchNorm[T]) Parameters() iter.Seq[*Value[T]] {
+	return values(bn.NamedParameters())
+}
+
+func (bn *BatchNorm[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return normParameters(bn.gamma, bn.beta)
+}
+
+// NamedBuffers yields the running statistics, e.g. "running_mean.0"
+func (bn *BatchNorm[T]) NamedBuffers() iter.Seq2[string, *T] {
+	return func(yield func(string, *T) bool) {
+		for i := range bn.runningMean {
+			if !yield(fmt.Sprintf("running_mean.%d", i), &bn.runningMean[i]) {
+				return
+			}
+		}
+		for i := range bn.runningVar {
+			if !yield(fmt.Sprintf("running_var.%d", i), &bn.runningVar[i]) {
+				return
+			}
+		}
+	}
+}
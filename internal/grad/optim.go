@@ -0,0 +1,108 @@
+package grad
+
+import (
+	"fmt"
+	"iter"
+	"math"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Optim names an optimizer so that it can be chosen by configuration
+type Optim string
+
+const (
+	SGDOptim      Optim = "sgd"
+	MomentumOptim Optim = "momentum"
+	AdamOptim     Optim = "adam"
+)
+
+// Optimizer updates parameters from their gradients. Optimizers with
+// state keep it per parameter, so the same parameters should be given
+// to each step.
+type Optimizer[T constraints.Float] interface {
+	Step(params iter.Seq[*Value[T]], lr T)
+}
+
+// NewOptimizer returns an optimizer with the usual defaults, an empty
+// name is SGD
+func NewOptimizer[T constraints.Float](name Optim) (Optimizer[T], error) {
+	switch name {
+	case "", SGDOptim:
+		return SGD[T]{}, nil
+	case MomentumOptim:
+		return NewMomentum[T](0.9), nil
+	case AdamOptim:
+		return NewAdam[T](0.9, 0.999, 1e-8), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownOptim, name)
+}
+
+// SGD is plain gradient descent, p -= lr * grad
+type SGD[T constraints.Float] struct{}
+
+func (SGD[T]) Step(params iter.Seq[*Value[T]], lr T) {
+	for p := range params {
+		p.Descend(-lr)
+	}
+}
+
+// Momentum is gradient descent with a velocity which accumulates the
+// gradients, v = beta*v + grad and p -= lr * v
+type Momentum[T constraints.Float] struct {
+	Beta     T
+	velocity map[*Value[T]]T
+}
+
+func NewMomentum[T constraints.Float](beta T) *Momentum[T] {
+	return &Momentum[T]{
+		Beta:     beta,
+		velocity: make(map[*Value[T]]T),
+	}
+}
+
+func (m *Momentum[T]) Step(params iter.Seq[*Value[T]], lr T) {
+	for p := range params {
+		v := m.Beta*m.velocity[p] + p.grad
+		m.velocity[p] = v
+		p.data -= lr * v
+	}
+}
+
+// Adam scales each parameter's step by running estimates of the mean
+// and variance of its gradient, see Kingma and Ba 2014
+type Adam[T constraints.Float] struct {
+	Beta1   T
+	Beta2   T
+	Epsilon T
+	t       int
+	m       map[*Value[T]]T
+	v       map[*Value[T]]T
+}
+
+func NewAdam[T constraints.Float](beta1, beta2, epsilon T) *Adam[T] {
+	return &Adam[T]{
+		Beta1:   beta1,
+		Beta2:   beta2,
+		Epsilon: epsilon,
+		m:       make(map[*Value[T]]T),
+		v:       make(map[*Value[T]]T),
+	}
+}
+
+func (a *Adam[T]) Step(params iter.Seq[*Value[T]], lr T) {
+	a.t++
+
+	// Bias corrections for the estimates starting at zero
+	c1 := 1 - T(math.Pow(float64(a.Beta1), float64(a.t)))
+	c2 := 1 - T(math.Pow(float64(a.Beta2), float64(a.t)))
+
+	for p := range params {
+		m := a.Beta1*a.m[p] + (1-a.Beta1)*p.grad
+		v := a.Beta2*a.v[p] + (1-a.Beta2)*p.grad*p.grad
+		a.m[p], a.v[p] = m, v
+
+		p.data -= lr * (m / c1) / (T(math.Sqrt(float64(v/c2))) + a.Epsilon)
+	}
+}
@@ -0,0 +1,146 @@
+package grad
+
+import (
+	"iter"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Regularizer penalises parameters by L1*sum(|p|) + L2*sum(p^2). It can
+// either be added to the loss with Term or applied directly to the
+// parameters after each step with Decay.
+type Regularizer[T constraints.Float] struct {
+	L1 T
+	L2 T
+}
+
+func L1[T constraints.Float](coef T) Regularizer[T] {
+	return Regularizer[T]{L1: coef}
+}
+
+func L2[T constraints.Float](coef T) Regularizer[T] {
+	return Regularizer[T]{L2: coef}
+}
+
+// ElasticNet mixes L1 and L2 like scikit-learn, l1Ratio of 1 is pure L1
+// and 0 is pure L2
+func ElasticNet[T constraints.Float](alpha T, l1Ratio T) Regularizer[T] {
+	return Regularizer[T]{
+		L1: alpha * l1Ratio,
+		L2: alpha * (1 - l1Ratio),
+	}
+}
+
+// Term returns the penalty as a graph node. It uses SumSq and SumAbs so
+// it only adds a handful of nodes regardless of the number of params.
+func (r Regularizer[T]) Term(c *Context[T], params iter.Seq[*Value[T]]) *Value[T] {
+	var ps []*Value[T]
+	for p := range params {
+		ps = append(ps, p)
+	}
+
+	return r.term(c, ps)
+}
+
+func (r Regularizer[T]) term(c *Context[T], ps []*Value[T]) *Value[T] {
+	term := c.Val(0, c.WithLabel("reg"))
+
+	if r.L1 != 0 {
+		term = term.Add(c.SumAbs(ps).Mul(c.Val(r.L1)), c.WithLabel("reg"))
+	}
+	if r.L2 != 0 {
+		term = term.Add(c.SumSq(ps).Mul(c.Val(r.L2)), c.WithLabel("reg"))
+	}
+
+	return term
+}
+
+// Decay applies the gradient of the penalty directly to the params,
+// scaled by the learning rate lr. This is decoupled weight decay, it is
+// used instead of Term and does not add anything to the graph.
+func (r Regularizer[T]) Decay(params iter.Seq[*Value[T]], lr T) {
+	for p := range params {
+		r.decay(p, lr)
+	}
+}
+
+func (r Regularizer[T]) decay(p *Value[T], lr T) {
+	update := 2 * r.L2 * p.data
+
+	if p.data > 0 {
+		update += r.L1
+	} else if p.data < 0 {
+		update -= r.L1
+	}
+
+	p.data -= lr * update
+}
+
+// ParamGroup applies a regularizer to the parameters whose names are
+// matched. A nil Match matches everything.
+type ParamGroup[T constraints.Float] struct {
+	Match func(name string) bool
+	Reg   Regularizer[T]
+}
+
+// Regularization applies different regularizers to groups of named
+// parameters. Each parameter belongs to the first group that matches
+// it, parameters which match no group are not regularized.
+type Regularization[T constraints.Float] []ParamGroup[T]
+
+func (rs Regularization[T]) group(name string) int {
+	for i, g := range rs {
+		if g.Match == nil || g.Match(name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (rs Regularization[T]) Term(c *Context[T], params iter.Seq2[string, *Value[T]]) *Value[T] {
+	groups := make([][]*Value[T], len(rs))
+	for name, p := range params {
+		if i := rs.group(name); i >= 0 {
+			groups[i] = append(groups[i], p)
+		}
+	}
+
+	terms := make([]*Value[T], len(rs))
+	for i, g := range rs {
+		terms[i] = g.Reg.term(c, groups[i])
+	}
+
+	if len(terms) < 1 {
+		return c.Val(0, c.WithLabel("reg"))
+	}
+
+	return c.Sum(terms)
+}
+
+func (rs Regularization[T]) Decay(params iter.Seq2[string, *Value[T]], lr T) {
+	for name, p := range params {
+		if i := rs.group(name); i >= 0 {
+			rs[i].Reg.decay(p, lr)
+		}
+	}
+}
+
+// IsBias matches the names of neuron biases and normalisation shifts
+func IsBias(name string) bool {
+	parts := strings.Split(name, ".")
+	last := parts[len(parts)-1]
+
+	if last == "b" {
+		return true
+	}
+
+	return len(parts) > 1 && parts[len(parts)-2] == "beta"
+}
+
+// WeightsOnly matches every parameter which is not a bias, for
+// excluding biases from regularization
+func WeightsOnly(name string) bool {
+	return !IsBias(name)
+}
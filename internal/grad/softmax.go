@@ -0,0 +1,131 @@
+package grad
+
+import (
+	"fmt"
+	"iter"
+
+	"golang.org/x/exp/constraints"
+)
+
+func maxData[T constraints.Float](vs []*Value[T]) T {
+	m := vs[0].data
+	for _, v := range vs[1:] {
+		if v.data > m {
+			m = v.data
+		}
+	}
+
+	return m
+}
+
+// Softmax turns logits into probabilities. The largest logit is
+// subtracted first so that Exp does not overflow, this is a constant so
+// it does not change the gradient. It panics if there are no logits, see
+// SoftmaxE.
+func (c *Context[T]) Softmax(logits []*Value[T]) []*Value[T] {
+	probs, err := c.SoftmaxE(logits)
+	if err != nil {
+		panic(err)
+	}
+
+	return probs
+}
+
+func (c *Context[T]) SoftmaxE(logits []*Value[T]) ([]*Value[T], error) {
+	if len(logits) < 1 {
+		return nil, fmt.Errorf("%w: softmax of no logits", ErrInputSize)
+	}
+
+	shift := c.Val(-maxData(logits))
+	exps := make([]*Value[T], len(logits))
+
+	for i, l := range logits {
+		exps[i] = l.Add(shift).Exp()
+	}
+
+	sum := c.Sum(exps)
+	probs := make([]*Value[T], len(logits))
+	for i, e := range exps {
+		probs[i] = e.Div(sum, c.WithLabel("p"))
+	}
+
+	return probs, nil
+}
+
+// CrossEntropy is the negative log of the softmax probability given to
+// label. It is calculated with log-sum-exp directly from the logits
+// which is more stable than taking the log of Softmax. It panics if
+// there are no logits or label is not one of them, see CrossEntropyE.
+func (c *Context[T]) CrossEntropy(logits []*Value[T], label int) *Value[T] {
+	loss, err := c.CrossEntropyE(logits, label)
+	if err != nil {
+		panic(err)
+	}
+
+	return loss
+}
+
+func (c *Context[T]) CrossEntropyE(logits []*Value[T], label int) (*Value[T], error) {
+	if len(logits) < 1 {
+		return nil, fmt.Errorf("%w: cross-entropy of no logits", ErrInputSize)
+	}
+	if label < 0 || label >= len(logits) {
+		return nil, fmt.Errorf("%w: label %d with %d logits", ErrBadLabel, label, len(logits))
+	}
+
+	m := maxData(logits)
+	shift := c.Val(-m)
+	exps := make([]*Value[T], len(logits))
+
+	for i, l := range logits {
+		exps[i] = l.Add(shift).Exp()
+	}
+
+	lse := c.Sum(exps).Log().Add(c.Val(m))
+
+	return lse.Sub(logits[label], c.WithLabel("loss")), nil
+}
+
+// Argmax returns the index of the largest value, i.e. the predicted
+// class when given logits or probabilities
+func Argmax[T constraints.Float](vs []*Value[T]) int {
+	best := 0
+	for i, v := range vs {
+		if v.data > vs[best].data {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// Softmax is a module with no parameters that applies Context.Softmax
+// to its inputs. The zero value is ready to use.
+type Softmax[T constraints.Float] struct{}
+
+var _ Module[float64] = Softmax[float64]{}
+
+func (s Softmax[T]) Forward(inputs []*Value[T]) []*Value[T] {
+	outs, err := s.ForwardE(inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return outs
+}
+
+func (Softmax[T]) ForwardE(inputs []*Value[T]) ([]*Value[T], error) {
+	if len(inputs) < 1 {
+		return nil, fmt.Errorf("%w: softmax of no inputs", ErrInputSize)
+	}
+
+	return inputs[0].ctx.SoftmaxE(inputs)
+}
+
+func (Softmax[T]) Parameters() iter.Seq[*Value[T]] {
+	return func(yield func(*Value[T]) bool) {}
+}
+
+func (Softmax[T]) NamedParameters() iter.Seq2[string, *Value[T]] {
+	return func(yield func(string, *Value[T]) bool) {}
+}
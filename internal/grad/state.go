@@ -0,0 +1,123 @@
+package grad
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+)
+
+// StateDict maps hierarchical parameter and buffer names to their data.
+// It is a snapshot, changing it does not affect the model it was taken
+// from.
+type StateDict[T constraints.Float] map[string]T
+
+// ParamDiff describes a parameter which differs between two state dicts.
+// If the parameter is missing from one side then InA or InB is false.
+type ParamDiff[T constraints.Float] struct {
+	Name string
+	A    T
+	B    T
+	InA  bool
+	InB  bool
+}
+
+func (d ParamDiff[T]) Delta() T {
+	return d.B - d.A
+}
+
+// paramData yields pointers to the data of each parameter
+func paramData[T constraints.Float](params iter.Seq2[string, *Value[T]]) iter.Seq2[string, *T] {
+	return func(yield func(string, *T) bool) {
+		for name, p := range params {
+			if !yield(name, &p.data) {
+				return
+			}
+		}
+	}
+}
+
+func stateDict[T constraints.Float](state iter.Seq2[string, *T]) StateDict[T] {
+	sd := make(StateDict[T])
+
+	for name, d := range state {
+		sd[name] = *d
+	}
+
+	return sd
+}
+
+// loadStateDict copies the data in sd into the matching parameters.
+// Parameters not in sd are left alone so that partial loads are
+// possible. Names in sd which are not parameters are an error and
+// nothing is loaded.
+func loadStateDict[T constraints.Float](state iter.Seq2[string, *T], sd StateDict[T]) error {
+	byName := make(map[string]*T)
+	for name, d := range state {
+		byName[name] = d
+	}
+
+	var unexpected []string
+	for name := range sd {
+		if _, ok := byName[name]; !ok {
+			unexpected = append(unexpected, name)
+		}
+	}
+
+	if len(unexpected) > 0 {
+		sort.Strings(unexpected)
+		return fmt.Errorf("load state dict: unexpected parameters: %s", strings.Join(unexpected, ", "))
+	}
+
+	for name, d := range sd {
+		*byName[name] = d
+	}
+
+	return nil
+}
+
+// Keys returns the parameter names in sorted order
+func (sd StateDict[T]) Keys() []string {
+	keys := make([]string, 0, len(sd))
+	for k := range sd {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Diff compares sd (A) with o (B) and returns the parameters which are
+// only present in one of them or whose values differ by more than tol.
+// The result is sorted by name.
+func (sd StateDict[T]) Diff(o StateDict[T], tol T) []ParamDiff[T] {
+	var diffs []ParamDiff[T]
+
+	for name, a := range sd {
+		b, ok := o[name]
+		if !ok {
+			diffs = append(diffs, ParamDiff[T]{Name: name, A: a, InA: true})
+			continue
+		}
+
+		// NaN is never greater than tol, so check for it separately
+		if T(math.Abs(float64(b-a))) > tol || math.IsNaN(float64(a)) != math.IsNaN(float64(b)) {
+			diffs = append(diffs, ParamDiff[T]{Name: name, A: a, B: b, InA: true, InB: true})
+		}
+	}
+
+	for name, b := range o {
+		if _, ok := sd[name]; !ok {
+			diffs = append(diffs, ParamDiff[T]{Name: name, B: b, InB: true})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs
+}
@@ -0,0 +1,498 @@
+package search
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/richiejp/micrograd/internal/config"
+	"github.com/richiejp/micrograd/internal/train"
+)
+
+// Strategy chooses the configurations tried by Run
+type Strategy string
+
+const (
+	// Grid tries every combination of the parameters' values
+	Grid Strategy = "grid"
+	// Random samples Trials configurations
+	Random Strategy = "random"
+	// Halving samples Trials configurations and trains them for a
+	// fraction of the epochs, then repeatedly keeps the best 1/Eta and
+	// gives them Eta times the epochs until one is trained in full.
+	// Each round trains from scratch.
+	Halving Strategy = "halving"
+)
+
+// Strategies are the choices of Strategy
+var Strategies = []Strategy{Grid, Random, Halving}
+
+// Metric is the validation metric configurations are ranked by
+type Metric string
+
+const (
+	// Accuracy ranks the highest first, ties are broken by loss
+	Accuracy Metric = "accuracy"
+	// Loss ranks the lowest first, ties are broken by accuracy
+	Loss Metric = "loss"
+)
+
+// ErrNoValidation is returned if the experiment holds out no data to
+// rank the trials by
+var ErrNoValidation = errors.New("search needs a validation set, dataset.val is 0")
+
+// ErrDiverged is the error of a trial whose loss or parameters are NaN
+// or infinite, so that it is ranked with the failed trials
+var ErrDiverged = errors.New("training diverged")
+
+// Options for Run, the zero value is a grid search ranked by accuracy
+type Options struct {
+	Strategy Strategy
+	// Trials is the number of configurations sampled by random search
+	// and successive halving, default 10
+	Trials int
+	Metric Metric
+	// Eta is the factor successive halving reduces the configurations
+	// by each round, default 3
+	Eta int
+	// Parallel is the number of trials run at once, default GOMAXPROCS
+	Parallel int
+	// Seed for sampling configurations
+	Seed int64
+	// If set then it is called as each trial finishes, from one
+	// goroutine at a time
+	OnTrial func(Trial)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Strategy == "" {
+		o.Strategy = Grid
+	}
+	if o.Trials == 0 {
+		o.Trials = 10
+	}
+	if o.Metric == "" {
+		o.Metric = Accuracy
+	}
+	if o.Eta == 0 {
+		o.Eta = 3
+	}
+	if o.Parallel < 1 {
+		o.Parallel = runtime.GOMAXPROCS(0)
+	}
+
+	return o
+}
+
+// Validate returns an error if the options, with their defaults filled
+// in, can't be used to search space
+func (o Options) Validate(space Space) error {
+	o = o.withDefaults()
+
+	if err := space.Validate(); err != nil {
+		return err
+	}
+	if o.Trials < 1 {
+		return fmt.Errorf("need at least one trial, not %d", o.Trials)
+	}
+	if o.Eta < 2 {
+		return fmt.Errorf("eta must be at least 2, not %d", o.Eta)
+	}
+	switch o.Metric {
+	case Accuracy, Loss:
+	default:
+		return fmt.Errorf("unknown metric %q, want accuracy or loss", o.Metric)
+	}
+
+	switch o.Strategy {
+	case Grid, Random:
+	case Halving:
+		for _, p := range space.Params {
+			if p.Name == "schedule.epochs" {
+				return errors.New("successive halving chooses schedule.epochs, it can't be searched")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown strategy %q", o.Strategy)
+	}
+
+	return nil
+}
+
+// Trial is the training of one configuration
+type Trial struct {
+	// ID numbers the configuration, the rounds of successive halving
+	// train the same configuration more than once
+	ID     int
+	Round  int
+	Params []Assignment
+	// Experiment is the base with the Params applied and the epochs of
+	// the round
+	Experiment config.Experiment
+	Train      train.Metrics
+	Val        train.Metrics
+	Duration   time.Duration
+	Err        error
+}
+
+// Results of a search, the trials are ranked best first
+type Results struct {
+	Strategy Strategy
+	Metric   Metric
+	// Params are the names of the searched parameters
+	Params []string
+	Trials []Trial
+}
+
+// Best is the highest ranked trial, it is false if every trial failed
+func (r *Results) Best() (Trial, bool) {
+	if len(r.Trials) < 1 || r.Trials[0].Err != nil {
+		return Trial{}, false
+	}
+
+	return r.Trials[0], true
+}
+
+// compare orders a before b if it is better by the metric, ties are
+// broken by the other one
+func (m Metric) compare(a, b train.Metrics) int {
+	acc := cmp.Compare(b.Accuracy, a.Accuracy)
+	loss := cmp.Compare(a.Loss, b.Loss)
+	if m == Loss {
+		return cmp.Or(loss, acc)
+	}
+
+	return cmp.Or(acc, loss)
+}
+
+// rank sorts failed trials last and later rounds, which were trained
+// for longer, first
+func rank(trials []Trial, m Metric) {
+	slices.SortStableFunc(trials, func(a, b Trial) int {
+		if (a.Err == nil) != (b.Err == nil) {
+			if a.Err == nil {
+				return -1
+			}
+			return 1
+		}
+
+		return cmp.Or(
+			cmp.Compare(b.Round, a.Round),
+			m.compare(a.Val, b.Val),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+}
+
+// dataset is a loaded dataset shared by the trials using it
+type dataset struct {
+	X [][]float64
+	y []int
+}
+
+// fit trains the experiment and measures it on the training and
+// validation sets
+func fit(e *config.Experiment, ds dataset) (train.Metrics, train.Metrics, error) {
+	trainX, trainY, valX, valY, err := e.Dataset.Split(ds.X, ds.y)
+	if err != nil {
+		return train.Metrics{}, train.Metrics{}, err
+	}
+
+	pre, err := e.Dataset.Preprocess()
+	if err != nil {
+		return train.Metrics{}, train.Metrics{}, err
+	}
+	if pre != nil {
+		if err := pre.Fit(trainX); err != nil {
+			return train.Metrics{}, train.Metrics{}, fmt.Errorf("preprocess: %w", err)
+		}
+	}
+
+	classes := 2
+	for _, yi := range ds.y {
+		classes = max(classes, yi+1)
+	}
+
+	opts := e.Options()
+	c, err := train.New(uint(len(ds.X[0])), classes, opts)
+	if err != nil {
+		return train.Metrics{}, train.Metrics{}, err
+	}
+	c.SetPreprocess(pre)
+
+	if err := c.Train(trainX, trainY, opts); err != nil {
+		return train.Metrics{}, train.Metrics{}, err
+	}
+
+	trainM, err := c.Evaluate(trainX, trainY)
+	if err != nil {
+		return train.Metrics{}, train.Metrics{}, err
+	}
+
+	valM, err := c.Evaluate(valX, valY)
+	if err != nil {
+		return train.Metrics{}, train.Metrics{}, fmt.Errorf("validation: %w", err)
+	}
+
+	// The ReLU of the max-margin loss turns NaN into 0, so the
+	// parameters are checked as well
+	for _, loss := range []float64{trainM.Loss, valM.Loss} {
+		if math.IsNaN(loss) || math.IsInf(loss, 0) {
+			return trainM, valM, fmt.Errorf("%w, the loss is %v", ErrDiverged, loss)
+		}
+	}
+	for name, p := range c.Model().NamedParameters() {
+		if v := p.Data(); math.IsNaN(v) || math.IsInf(v, 0) {
+			return trainM, valM, fmt.Errorf("%w, %s is %v", ErrDiverged, name, v)
+		}
+	}
+
+	return trainM, valM, nil
+}
+
+// runner trains trials in parallel
+type runner struct {
+	opts     Options
+	datasets map[config.Dataset]dataset
+}
+
+// load reads each distinct dataset of the trials once
+func (r *runner) load(trials []Trial) error {
+	for _, t := range trials {
+		d := t.Experiment.Dataset
+		if _, ok := r.datasets[d]; ok {
+			continue
+		}
+
+		X, y, err := d.Load()
+		if err != nil {
+			return err
+		}
+		if len(X) < 1 {
+			return errors.New("no samples")
+		}
+
+		r.datasets[d] = dataset{X: X, y: y}
+	}
+
+	return nil
+}
+
+// run trains the trials and returns them in the order given
+func (r *runner) run(trials []Trial) []Trial {
+	jobs := make(chan int)
+	done := make(chan int)
+
+	for range min(r.opts.Parallel, len(trials)) {
+		go func() {
+			for i := range jobs {
+				t := &trials[i]
+				start := time.Now()
+				t.Train, t.Val, t.Err = fit(&t.Experiment, r.datasets[t.Experiment.Dataset])
+				t.Duration = time.Since(start)
+				done <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := range trials {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for range trials {
+		i := <-done
+		if r.opts.OnTrial != nil {
+			r.opts.OnTrial(trials[i])
+		}
+	}
+
+	return trials
+}
+
+// Run trains configurations of base drawn from the space and ranks them
+// by their validation metric. Failed trials are included with their
+// error, an error is only returned if the search can't start.
+func Run(base config.Experiment, space Space, opts Options) (*Results, error) {
+	opts = opts.withDefaults()
+
+	if err := opts.Validate(space); err != nil {
+		return nil, err
+	}
+
+	var configs [][]Assignment
+	if opts.Strategy == Grid {
+		var err error
+		if configs, err = space.Grid(); err != nil {
+			return nil, err
+		}
+	} else {
+		configs = space.Sample(opts.Trials, opts.Seed)
+	}
+
+	trials := make([]Trial, len(configs))
+	for i, c := range configs {
+		e, err := Apply(base, c)
+		if err != nil {
+			return nil, fmt.Errorf("trial %d: %w", i, err)
+		}
+		if e.Dataset.Val <= 0 {
+			return nil, ErrNoValidation
+		}
+		trials[i] = Trial{ID: i, Params: c, Experiment: *e}
+	}
+
+	r := &runner{opts: opts, datasets: map[config.Dataset]dataset{}}
+	if err := r.load(trials); err != nil {
+		return nil, err
+	}
+
+	res := &Results{Strategy: opts.Strategy, Metric: opts.Metric}
+	for _, p := range space.Params {
+		res.Params = append(res.Params, p.Name)
+	}
+
+	if opts.Strategy == Halving {
+		res.Trials = r.halve(trials, base.Schedule.Epochs)
+	} else {
+		res.Trials = r.run(trials)
+	}
+	rank(res.Trials, opts.Metric)
+
+	return res, nil
+}
+
+// halve runs the rounds of successive halving, each has 1/Eta of the
+// configurations of the last with Eta times the epochs
+func (r *runner) halve(candidates []Trial, epochs int) []Trial {
+	eta := r.opts.Eta
+
+	rounds := 1
+	for n := len(candidates); n > 1; n = (n + eta - 1) / eta {
+		rounds++
+	}
+
+	budget := epochs
+	for range rounds - 1 {
+		budget /= eta
+	}
+
+	var all []Trial
+	for round := 0; ; round++ {
+		last := len(candidates) <= 1 || round == rounds-1
+		if last {
+			budget = epochs
+		}
+
+		for i := range candidates {
+			candidates[i].Round = round
+			candidates[i].Experiment.Schedule.Epochs = max(1, budget)
+		}
+		candidates = r.run(candidates)
+		all = append(all, candidates...)
+
+		if last {
+			break
+		}
+
+		ranked := slices.Clone(candidates)
+		rank(ranked, r.opts.Metric)
+
+		keep := (len(ranked) + eta - 1) / eta
+		candidates = nil
+		for _, t := range ranked[:keep] {
+			if t.Err != nil {
+				break
+			}
+			t.Train, t.Val, t.Err, t.Duration = train.Metrics{}, train.Metrics{}, nil, 0
+			candidates = append(candidates, t)
+		}
+		if len(candidates) < 1 {
+			break
+		}
+
+		budget = min(epochs, budget*eta)
+	}
+
+	return all
+}
+
+func (r *Results) header() []string {
+	h := []string{"rank", "trial", "round", "epochs"}
+	h = append(h, r.Params...)
+
+	return append(h, "val_loss", "val_accuracy", "train_loss", "train_accuracy", "seconds", "error")
+}
+
+func (r *Results) row(rank int, t Trial) []string {
+	row := []string{
+		strconv.Itoa(rank),
+		strconv.Itoa(t.ID),
+		strconv.Itoa(t.Round),
+		strconv.Itoa(t.Experiment.Schedule.Epochs),
+	}
+
+	for _, name := range r.Params {
+		value := ""
+		for _, a := range t.Params {
+			if a.Name == name {
+				value = formatValue(a.Value)
+			}
+		}
+		row = append(row, value)
+	}
+
+	if t.Err != nil {
+		return append(row, "", "", "", "", "", t.Err.Error())
+	}
+
+	return append(row,
+		strconv.FormatFloat(t.Val.Loss, 'f', 4, 64),
+		strconv.FormatFloat(t.Val.Accuracy, 'f', 4, 64),
+		strconv.FormatFloat(t.Train.Loss, 'f', 4, 64),
+		strconv.FormatFloat(t.Train.Accuracy, 'f', 4, 64),
+		strconv.FormatFloat(t.Duration.Seconds(), 'f', 2, 64),
+		"",
+	)
+}
+
+// WriteCSV writes the ranked trials with a column per parameter
+func (r *Results) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(r.header())
+
+	for i, t := range r.Trials {
+		cw.Write(r.row(i+1, t))
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WriteTable writes the ranked trials as aligned text, top limits the
+// rows if it is above 0
+func (r *Results) WriteTable(w io.Writer, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(r.header(), "\t"))
+
+	for i, t := range r.Trials {
+		if top > 0 && i >= top {
+			break
+		}
+		fmt.Fprintln(tw, strings.Join(r.row(i+1, t), "\t"))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,281 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/richiejp/micrograd/internal/config"
+)
+
+// Param is a hyperparameter named by its path in an experiment file,
+// e.g. optimizer.learning_rate or model.hidden
+type Param struct {
+	Name string `yaml:"name" json:"name"`
+	// Values to choose from, anything the experiment's field accepts
+	// such as [16, 16] for model.hidden
+	Values []any `yaml:"values,omitempty" json:"values,omitempty"`
+	// Min and Max bound a numeric range used when there are no Values
+	Min float64 `yaml:"min,omitempty" json:"min,omitempty"`
+	Max float64 `yaml:"max,omitempty" json:"max,omitempty"`
+	// Log spaces the range logarithmically, e.g. for learning rates
+	Log bool `yaml:"log,omitempty" json:"log,omitempty"`
+	// Int rounds points of the range to whole numbers
+	Int bool `yaml:"int,omitempty" json:"int,omitempty"`
+	// Steps is the number of evenly spaced points of the range used by
+	// grid search
+	Steps int `yaml:"steps,omitempty" json:"steps,omitempty"`
+}
+
+// Space is the set of hyperparameters searched
+type Space struct {
+	Params []Param `yaml:"params" json:"params"`
+}
+
+// Assignment is the value given to a parameter in a trial
+type Assignment struct {
+	Name  string
+	Value any
+}
+
+func (a Assignment) String() string {
+	return a.Name + "=" + formatValue(a.Value)
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []any:
+		parts := make([]string, len(v))
+		for i, vi := range v {
+			parts[i] = formatValue(vi)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	return fmt.Sprint(v)
+}
+
+// ReadSpace decodes a space, unknown fields are an error
+func ReadSpace(r io.Reader, format config.Format) (*Space, error) {
+	var s Space
+
+	switch format {
+	case config.JSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&s); err != nil {
+			return nil, err
+		}
+	case config.YAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&s); err != nil && err != io.EOF {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// LoadSpace reads a space from a YAML or JSON file
+func LoadSpace(path string) (*Space, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	s, err := ReadSpace(file, config.FormatOf(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Validate checks each parameter has values or a range, whether the
+// names exist is only known when they are applied to an experiment
+func (s *Space) Validate() error {
+	var errs []error
+	seen := map[string]bool{}
+
+	for i, p := range s.Params {
+		field := fmt.Sprintf("params[%d]", i)
+		if p.Name != "" {
+			field = p.Name
+		}
+		check := func(ok bool, format string, args ...any) {
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+			}
+		}
+
+		check(p.Name != "", "a name is needed")
+		check(!seen[p.Name], "given more than once")
+		seen[p.Name] = true
+
+		if len(p.Values) > 0 {
+			continue
+		}
+
+		check(p.Min != 0 || p.Max != 0, "values or a range are needed")
+		check(p.Min <= p.Max, "min %v is above max %v", p.Min, p.Max)
+		check(!p.Log || p.Min > 0, "a log range must be above 0")
+		check(p.Steps >= 0, "steps must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// point returns the value at t in [0, 1] along the range
+func (p Param) point(t float64) any {
+	var v float64
+	if p.Log {
+		v = math.Exp(math.Log(p.Min) + t*(math.Log(p.Max)-math.Log(p.Min)))
+	} else {
+		v = p.Min + t*(p.Max-p.Min)
+	}
+
+	if p.Int {
+		return int(math.Round(v))
+	}
+
+	// Three significant figures keep the results readable
+	v, _ = strconv.ParseFloat(strconv.FormatFloat(v, 'g', 3, 64), 64)
+
+	return v
+}
+
+// grid returns the values tried by grid search
+func (p Param) grid() ([]any, error) {
+	if len(p.Values) > 0 {
+		return p.Values, nil
+	}
+
+	switch p.Steps {
+	case 0:
+		return nil, fmt.Errorf("%s: grid search needs values or steps", p.Name)
+	case 1:
+		return []any{p.point(0)}, nil
+	}
+
+	var values []any
+	for i := range p.Steps {
+		v := p.point(float64(i) / float64(p.Steps-1))
+		// Rounding to integers can repeat points
+		if len(values) > 0 && v == values[len(values)-1] {
+			continue
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// sample returns a random value, uniform over Values or the range
+func (p Param) sample(rng *rand.Rand) any {
+	if len(p.Values) > 0 {
+		return p.Values[rng.Intn(len(p.Values))]
+	}
+
+	return p.point(rng.Float64())
+}
+
+// Grid returns every combination of the parameters' grid values
+func (s *Space) Grid() ([][]Assignment, error) {
+	configs := [][]Assignment{nil}
+
+	for _, p := range s.Params {
+		values, err := p.grid()
+		if err != nil {
+			return nil, err
+		}
+
+		var next [][]Assignment
+		for _, c := range configs {
+			for _, v := range values {
+				a := append(append([]Assignment(nil), c...), Assignment{Name: p.Name, Value: v})
+				next = append(next, a)
+			}
+		}
+		configs = next
+	}
+
+	return configs, nil
+}
+
+// Sample returns n random configurations
+func (s *Space) Sample(n int, seed int64) [][]Assignment {
+	rng := rand.New(rand.NewSource(seed))
+	configs := make([][]Assignment, n)
+
+	for i := range configs {
+		for _, p := range s.Params {
+			configs[i] = append(configs[i], Assignment{Name: p.Name, Value: p.sample(rng)})
+		}
+	}
+
+	return configs
+}
+
+// Apply returns a copy of base with the assignments made, it is
+// validated like an experiment file
+func Apply(base config.Experiment, assignments []Assignment) (*config.Experiment, error) {
+	b, err := yaml.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]any
+	if err := yaml.Unmarshal(b, &tree); err != nil {
+		return nil, err
+	}
+
+	for _, a := range assignments {
+		if err := set(tree, strings.Split(a.Name, "."), a.Value); err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Name, err)
+		}
+	}
+
+	if b, err = yaml.Marshal(tree); err != nil {
+		return nil, err
+	}
+
+	return config.Read(bytes.NewReader(b), config.YAML)
+}
+
+func set(tree map[string]any, path []string, value any) error {
+	if len(path) == 1 {
+		tree[path[0]] = value
+		return nil
+	}
+
+	sub, ok := tree[path[0]]
+	if !ok {
+		return fmt.Errorf("unknown field %q", path[0])
+	}
+
+	m, ok := sub.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%q is not a section", path[0])
+	}
+
+	return set(m, path[1:], value)
+}
@@ -0,0 +1,99 @@
+package train
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+// Checkpoint is everything needed to restore a Classifier
+type Checkpoint struct {
+	Inputs     uint                    `json:"inputs"`
+	Classes    int                     `json:"classes"`
+	Hidden     []uint                  `json:"hidden"`
+	ActFn      grad.ActFn              `json:"act_fn"`
+	State      grad.StateDict[float64] `json:"state"`
+	Preprocess data.Pipeline           `json:"preprocess,omitempty"`
+}
+
+func (c *Classifier) Checkpoint() Checkpoint {
+	return Checkpoint{
+		Inputs:     c.nin,
+		Classes:    c.classes,
+		Hidden:     c.hidden,
+		ActFn:      c.actFn,
+		State:      c.model.StateDict(),
+		Preprocess: c.pre,
+	}
+}
+
+func (c *Classifier) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(c.Checkpoint())
+}
+
+func (c *Classifier) SaveFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Create %s: %w", path, err)
+	}
+
+	if err := c.Save(file); err != nil {
+		file.Close()
+		return fmt.Errorf("Save %s: %w", path, err)
+	}
+
+	return file.Close()
+}
+
+// Restore builds a classifier from a checkpoint
+func Restore(cp Checkpoint) (*Classifier, error) {
+	c, err := New(cp.Inputs, cp.Classes, Options{Hidden: cp.Hidden, ActFn: cp.ActFn})
+	if err != nil {
+		return nil, fmt.Errorf("restore: %w", err)
+	}
+
+	// A partial load would leave some parameters randomly initialised
+	for name := range c.model.NamedParameters() {
+		if _, ok := cp.State[name]; !ok {
+			return nil, fmt.Errorf("restore: missing parameter %s", name)
+		}
+	}
+
+	if err := c.model.LoadStateDict(cp.State); err != nil {
+		return nil, fmt.Errorf("restore: %w", err)
+	}
+	c.pre = cp.Preprocess
+
+	return c, nil
+}
+
+func Load(r io.Reader) (*Classifier, error) {
+	var cp Checkpoint
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	return Restore(cp)
+}
+
+func LoadFile(path string) (*Classifier, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	c, err := Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,44 @@
+package train
+
+// Step is the record of one gradient descent step. Training is full
+// batch so each step is also an epoch.
+type Step struct {
+	Step int `json:"step"`
+	// Train is measured on the training data before the update
+	Train Metrics `json:"train"`
+	// Val is measured on the validation data before the update, it is
+	// nil if there is no validation data
+	Val          *Metrics `json:"val,omitempty"`
+	LearningRate float64  `json:"learning_rate"`
+	// GradNorm is the global gradient norm before clipping
+	GradNorm float64 `json:"grad_norm"`
+}
+
+// History records the progress of training
+type History struct {
+	Steps []Step `json:"steps"`
+}
+
+func (h *History) Record(s Step) {
+	h.Steps = append(h.Steps, s)
+}
+
+// Last returns the most recent step or false if there are none
+func (h *History) Last() (Step, bool) {
+	if len(h.Steps) < 1 {
+		return Step{}, false
+	}
+
+	return h.Steps[len(h.Steps)-1], true
+}
+
+// HasVal is true if any step has validation metrics
+func (h *History) HasVal() bool {
+	for _, s := range h.Steps {
+		if s.Val != nil {
+			return true
+		}
+	}
+
+	return false
+}
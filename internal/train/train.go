@@ -0,0 +1,532 @@
+package train
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+// Options for training an MLP classifier with full batch gradient
+// descent, the defaults are those of demo() in main.go
+type Options struct {
+	// Hidden layer sizes, default 16, 16
+	Hidden []uint
+	// Activation of the hidden layers, default relu
+	ActFn grad.ActFn
+	// Number of gradient descent steps, default 100
+	Steps int
+	// Optimizer used for each step, default sgd
+	Optimizer grad.Optim
+	// Default 0.5, or 0.01 with Adam
+	LearningRate float64
+	// If above zero the learning rate decays linearly to this by the
+	// last step
+	FinalLearningRate float64
+	// Coefficient of the L2 regularization term
+	L2 float64
+	// Gradients are clipped to this global norm if it is above zero
+	ClipNorm float64
+	// Seed for the parameter initialisation
+	Seed uint64
+	// If set then each step is recorded in History
+	History *History
+	// If set then it is called with the record of each step, as would be
+	// added to History, e.g. to update a dashboard
+	OnStep func(Step)
+	// Validation data evaluated at each step, before the update, when
+	// recording history
+	ValX [][]float64
+	ValY []int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Hidden == nil {
+		o.Hidden = []uint{16, 16}
+	}
+	if o.ActFn == "" {
+		o.ActFn = grad.ReluActFn
+	}
+	if o.Steps == 0 {
+		o.Steps = 100
+	}
+	if o.Optimizer == "" {
+		o.Optimizer = grad.SGDOptim
+	}
+	if o.LearningRate == 0 {
+		o.LearningRate = 0.5
+		if o.Optimizer == grad.AdamOptim {
+			o.LearningRate = 0.01
+		}
+	}
+
+	return o
+}
+
+// learningRate returns the learning rate at step k
+func (o Options) learningRate(k int) float64 {
+	if o.FinalLearningRate <= 0 || o.Steps < 2 {
+		return o.LearningRate
+	}
+
+	frac := float64(k) / float64(o.Steps-1)
+
+	return o.LearningRate + frac*(o.FinalLearningRate-o.LearningRate)
+}
+
+// Metrics are the mean loss, without regularization, and the accuracy
+type Metrics struct {
+	Loss     float64 `json:"loss"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// Classifier is an MLP trained on integer class labels. With two
+// classes it has a single output trained with the SVM max-margin loss,
+// with more it has one output per class trained with cross-entropy.
+type Classifier struct {
+	ctx     *grad.Context[float64]
+	model   *grad.MLP[float64]
+	nin     uint
+	classes int
+	hidden  []uint
+	actFn   grad.ActFn
+	pre     data.Pipeline
+}
+
+func numClasses(X [][]float64, y []int) (int, error) {
+	if len(y) < 1 {
+		return 0, errors.New("no samples")
+	}
+	if len(X) != len(y) {
+		return 0, fmt.Errorf("%d samples but %d labels", len(X), len(y))
+	}
+
+	classes := 0
+	for i, yi := range y {
+		if yi < 0 {
+			return 0, fmt.Errorf("sample %d: negative label %d", i, yi)
+		}
+		classes = max(classes, yi+1)
+	}
+
+	return max(classes, 2), nil
+}
+
+func New(nin uint, classes int, opts Options) (*Classifier, error) {
+	if classes < 2 {
+		return nil, fmt.Errorf("new classifier: need at least 2 classes, got %d", classes)
+	}
+
+	opts = opts.withDefaults()
+
+	gc := &grad.Context[float64]{}
+	gc.Seed(opts.Seed)
+
+	nout := uint(classes)
+	if classes == 2 {
+		nout = 1
+	}
+
+	sz := append([]uint{nin}, opts.Hidden...)
+	sz = append(sz, nout)
+
+	actFns := make([]grad.ActFn, len(sz)-1)
+	for i := range actFns {
+		actFns[i] = opts.ActFn
+	}
+	actFns[len(actFns)-1] = grad.LinearActFn
+
+	model, err := gc.MLPWith(sz, gc.WithActFns(actFns...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Classifier{
+		ctx:     gc,
+		model:   model,
+		nin:     nin,
+		classes: classes,
+		hidden:  opts.Hidden,
+		actFn:   opts.ActFn,
+	}, nil
+}
+
+// Fit trains a new classifier on X and y
+func Fit(X [][]float64, y []int, opts Options) (*Classifier, error) {
+	classes, err := numClasses(X, y)
+	if err != nil {
+		return nil, fmt.Errorf("fit: %w", err)
+	}
+
+	c, err := New(uint(len(X[0])), classes, opts)
+	if err != nil {
+		return nil, fmt.Errorf("fit: %w", err)
+	}
+
+	if err := c.Train(X, y, opts); err != nil {
+		return nil, fmt.Errorf("fit: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *Classifier) Context() *grad.Context[float64] {
+	return c.ctx
+}
+
+func (c *Classifier) Model() *grad.MLP[float64] {
+	return c.model
+}
+
+func (c *Classifier) Classes() int {
+	return c.classes
+}
+
+// SetPreprocess sets a fitted pipeline which is applied to the features
+// given to Train, Evaluate and Predict and is saved in checkpoints
+func (c *Classifier) SetPreprocess(pre data.Pipeline) {
+	c.pre = pre
+}
+
+func (c *Classifier) Preprocess() data.Pipeline {
+	return c.pre
+}
+
+// preprocessed puts the classifier's preprocessing in front of its model
+type preprocessed struct {
+	c *Classifier
+}
+
+func (p preprocessed) ForwardE(inputs []*grad.Value[float64]) ([]*grad.Value[float64], error) {
+	x := make([]float64, len(inputs))
+	for i, in := range inputs {
+		x[i] = in.Data()
+	}
+
+	vals, err := p.c.inputs([][]float64{x})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.c.model.ForwardE(vals[0])
+}
+
+func (p preprocessed) Forward(inputs []*grad.Value[float64]) []*grad.Value[float64] {
+	out, err := p.ForwardE(inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return out
+}
+
+func (p preprocessed) Parameters() iter.Seq[*grad.Value[float64]] {
+	return p.c.model.Parameters()
+}
+
+func (p preprocessed) NamedParameters() iter.Seq2[string, *grad.Value[float64]] {
+	return p.c.model.NamedParameters()
+}
+
+// Module returns the model with the preprocessing in front of it, so
+// that it takes the same features as Predict, e.g. for
+// viz.BoundaryPlot. Gradients don't flow back through the
+// preprocessing.
+func (c *Classifier) Module() grad.Module[float64] {
+	return preprocessed{c: c}
+}
+
+func (c *Classifier) inputs(X [][]float64) ([][]*grad.Value[float64], error) {
+	if c.pre != nil {
+		var err error
+		if X, err = c.pre.Transform(X); err != nil {
+			return nil, err
+		}
+	}
+
+	inputs := make([][]*grad.Value[float64], len(X))
+	for i, xrow := range X {
+		inputs[i] = c.ctx.Vals(xrow...)
+	}
+
+	return inputs, nil
+}
+
+// loss builds the mean data loss over the batch and counts the correct
+// predictions
+func (c *Classifier) loss(inputs [][]*grad.Value[float64], y []int) (*grad.Value[float64], int, error) {
+	gc := c.ctx
+	losses := make([]*grad.Value[float64], len(inputs))
+	correct := 0
+
+	for i, input := range inputs {
+		out, err := c.model.ForwardE(input)
+		if err != nil {
+			return nil, 0, fmt.Errorf("sample %d: %w", i, err)
+		}
+
+		if y[i] < 0 || y[i] >= c.classes {
+			return nil, 0, fmt.Errorf("sample %d: label %d is not one of the %d classes, labels are from 0", i, y[i], c.classes)
+		}
+
+		if c.classes == 2 {
+			// SVM "max-margin" loss with labels mapped to -1 and 1
+			yi := float64(2*y[i] - 1)
+			losses[i] = gc.Val(1).Sub(gc.Val(yi).Mul(out[0])).Relu(gc.WithLabel("loss"))
+
+			if (yi > 0) == (out[0].Data() > 0) {
+				correct++
+			}
+		} else {
+			if losses[i], err = gc.CrossEntropyE(out, y[i]); err != nil {
+				return nil, 0, fmt.Errorf("sample %d: %w", i, err)
+			}
+
+			if grad.Argmax(out) == y[i] {
+				correct++
+			}
+		}
+	}
+
+	sum, err := gc.SumE(losses)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sum.Div(gc.Val(float64(len(losses)))), correct, nil
+}
+
+// validate measures the model on inputs with training behaviour, such
+// as dropout, switched off
+func (c *Classifier) validate(inputs [][]*grad.Value[float64], y []int) (*Metrics, error) {
+	c.model.SetTraining(false)
+	defer c.model.SetTraining(true)
+
+	loss, correct, err := c.loss(inputs, y)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		Loss:     loss.Data(),
+		Accuracy: float64(correct) / float64(len(y)),
+	}, nil
+}
+
+// Train runs opts.Steps of full batch gradient descent on X and y
+func (c *Classifier) Train(X [][]float64, y []int, opts Options) error {
+	opts = opts.withDefaults()
+	gc := c.ctx
+
+	if len(X) != len(y) {
+		return fmt.Errorf("%d samples but %d labels", len(X), len(y))
+	}
+
+	if len(opts.ValX) != len(opts.ValY) {
+		return fmt.Errorf("%d validation samples but %d labels", len(opts.ValX), len(opts.ValY))
+	}
+
+	optim, err := grad.NewOptimizer[float64](opts.Optimizer)
+	if err != nil {
+		return err
+	}
+
+	inputs, err := c.inputs(X)
+	if err != nil {
+		return err
+	}
+
+	recording := opts.History != nil || opts.OnStep != nil
+
+	var valInputs [][]*grad.Value[float64]
+	if recording && len(opts.ValX) > 0 {
+		if valInputs, err = c.inputs(opts.ValX); err != nil {
+			return fmt.Errorf("validation: %w", err)
+		}
+	}
+
+	reg := grad.L2(opts.L2)
+	c.model.SetTraining(true)
+	defer c.model.SetTraining(false)
+
+	for k := range opts.Steps {
+		var val *Metrics
+		if valInputs != nil {
+			if val, err = c.validate(valInputs, opts.ValY); err != nil {
+				return fmt.Errorf("step %d: validation: %w", k, err)
+			}
+		}
+
+		dataLoss, correct, err := c.loss(inputs, y)
+		if err != nil {
+			return err
+		}
+
+		total := dataLoss
+		if opts.L2 > 0 {
+			total = dataLoss.Add(reg.Term(gc, c.model.Parameters()))
+		}
+
+		if err := gc.Backward(total); err != nil {
+			return fmt.Errorf("step %d: %w", k, err)
+		}
+
+		var gradNorm float64
+		if opts.ClipNorm > 0 {
+			gradNorm = grad.ClipGradNorm(c.model.Parameters(), opts.ClipNorm)
+		} else if recording {
+			gradNorm = grad.GradNorm(c.model.Parameters())
+		}
+
+		lr := opts.learningRate(k)
+		optim.Step(c.model.Parameters(), lr)
+
+		if !recording {
+			continue
+		}
+
+		step := Step{
+			Step: k,
+			Train: Metrics{
+				Loss:     dataLoss.Data(),
+				Accuracy: float64(correct) / float64(len(y)),
+			},
+			Val:          val,
+			LearningRate: lr,
+			GradNorm:     gradNorm,
+		}
+
+		if opts.History != nil {
+			opts.History.Record(step)
+		}
+		if opts.OnStep != nil {
+			opts.OnStep(step)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate returns the loss and accuracy on X and y
+func (c *Classifier) Evaluate(X [][]float64, y []int) (Metrics, error) {
+	if len(X) < 1 {
+		return Metrics{}, errors.New("evaluate: no samples")
+	}
+
+	if len(X) != len(y) {
+		return Metrics{}, fmt.Errorf("evaluate: %d samples but %d labels", len(X), len(y))
+	}
+
+	inputs, err := c.inputs(X)
+	if err != nil {
+		return Metrics{}, fmt.Errorf("evaluate: %w", err)
+	}
+
+	loss, correct, err := c.loss(inputs, y)
+	if err != nil {
+		return Metrics{}, fmt.Errorf("evaluate: %w", err)
+	}
+
+	return Metrics{
+		Loss:     loss.Data(),
+		Accuracy: float64(correct) / float64(len(y)),
+	}, nil
+}
+
+// Predict returns the class of x
+func (c *Classifier) Predict(x []float64) (int, error) {
+	inputs, err := c.inputs([][]float64{x})
+	if err != nil {
+		return 0, fmt.Errorf("predict: %w", err)
+	}
+
+	out, err := c.model.ForwardE(inputs[0])
+	if err != nil {
+		return 0, fmt.Errorf("predict: %w", err)
+	}
+
+	if c.classes == 2 {
+		if out[0].Data() > 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return grad.Argmax(out), nil
+}
+
+// CVResult holds the metrics of each fold along with their mean and
+// standard deviation
+type CVResult struct {
+	Folds []Metrics
+	Mean  Metrics
+	Std   Metrics
+	// Histories has the steps of each fold if Options.History was set
+	Histories []*History
+}
+
+// CrossValidate trains a fresh classifier on each fold's training set
+// and evaluates it on the fold's test set. If opts.History is set then
+// each fold is recorded in its own History in CVResult.Histories and
+// opts.History is left as it is.
+func CrossValidate(X [][]float64, y []int, folds []data.Fold, opts Options) (*CVResult, error) {
+	classes, err := numClasses(X, y)
+	if err != nil {
+		return nil, fmt.Errorf("cross-validate: %w", err)
+	}
+
+	var res CVResult
+	for f, fold := range folds {
+		trainX, trainY := data.Take(X, y, fold.Train)
+		testX, testY := data.Take(X, y, fold.Test)
+
+		c, err := New(uint(len(X[0])), classes, opts)
+		if err != nil {
+			return nil, fmt.Errorf("cross-validate: %w", err)
+		}
+
+		foldOpts := opts
+		if opts.History != nil {
+			foldOpts.History = &History{}
+			res.Histories = append(res.Histories, foldOpts.History)
+		}
+
+		if err := c.Train(trainX, trainY, foldOpts); err != nil {
+			return nil, fmt.Errorf("cross-validate: fold %d: %w", f, err)
+		}
+
+		m, err := c.Evaluate(testX, testY)
+		if err != nil {
+			return nil, fmt.Errorf("cross-validate: fold %d: %w", f, err)
+		}
+
+		res.Folds = append(res.Folds, m)
+	}
+
+	res.Mean, res.Std = Aggregate(res.Folds)
+
+	return &res, nil
+}
+
+// Aggregate returns the mean and population standard deviation of ms
+func Aggregate(ms []Metrics) (Metrics, Metrics) {
+	var mean, std Metrics
+	n := float64(len(ms))
+
+	for _, m := range ms {
+		mean.Loss += m.Loss / n
+		mean.Accuracy += m.Accuracy / n
+	}
+
+	for _, m := range ms {
+		std.Loss += (m.Loss - mean.Loss) * (m.Loss - mean.Loss) / n
+		std.Accuracy += (m.Accuracy - mean.Accuracy) * (m.Accuracy - mean.Accuracy) / n
+	}
+	std.Loss = math.Sqrt(std.Loss)
+	std.Accuracy = math.Sqrt(std.Accuracy)
+
+	return mean, std
+}
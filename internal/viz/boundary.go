@@ -0,0 +1,255 @@
+package viz
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+
+	"golang.org/x/exp/constraints"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+// classColors are used for the data points, the decision regions use
+// lighter versions of them
+var classColors = []color.RGBA{
+	{R: 220, G: 40, B: 40, A: 255},
+	{R: 40, G: 80, B: 220, A: 255},
+	{R: 40, G: 160, B: 60, A: 255},
+	{R: 230, G: 150, B: 20, A: 255},
+	{R: 140, G: 60, B: 180, A: 255},
+	{R: 20, G: 170, B: 170, A: 255},
+	{R: 120, G: 90, B: 60, A: 255},
+	{R: 220, G: 90, B: 180, A: 255},
+}
+
+func classColor(class int) color.RGBA {
+	return classColors[class%len(classColors)]
+}
+
+// tint mixes c with white
+func tint(c color.RGBA, amount float64) color.RGBA {
+	mix := func(x uint8) uint8 {
+		return uint8(float64(x) + amount*(255-float64(x)))
+	}
+
+	return color.RGBA{R: mix(c.R), G: mix(c.G), B: mix(c.B), A: 255}
+}
+
+type classPalette []color.Color
+
+func (p classPalette) Colors() []color.Color {
+	return p
+}
+
+type grid struct {
+	xs []float64
+	ys []float64
+	z  [][]float64
+}
+
+func (g *grid) Dims() (int, int) {
+	return len(g.xs), len(g.ys)
+}
+
+func (g *grid) Z(c, r int) float64 {
+	return g.z[c][r]
+}
+
+func (g *grid) X(c int) float64 {
+	return g.xs[c]
+}
+
+func (g *grid) Y(r int) float64 {
+	return g.ys[r]
+}
+
+type BoundaryOptions struct {
+	// Number of grid points along each axis, default 60
+	Resolution int
+	// Extra space around the data as a fraction of its range, default 0.1
+	Margin float64
+	Title  string
+	// Size of the image, default 6 inches square
+	Width  vg.Length
+	Height vg.Length
+}
+
+func (o BoundaryOptions) withDefaults() BoundaryOptions {
+	if o.Resolution < 2 {
+		o.Resolution = 60
+	}
+	if o.Margin == 0 {
+		o.Margin = 0.1
+	}
+	if o.Title == "" {
+		o.Title = "Decision boundary"
+	}
+	if o.Width == 0 {
+		o.Width = 6 * vg.Inch
+	}
+	if o.Height == 0 {
+		o.Height = 6 * vg.Inch
+	}
+
+	return o
+}
+
+// predictClass turns the outputs of a classifier into a class. A single
+// output is a score where positive means class 1, otherwise the largest
+// output is the class.
+func predictClass[T constraints.Float](out []*grad.Value[T]) int {
+	if len(out) == 1 {
+		if out[0].Data() > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	return grad.Argmax(out)
+}
+
+// BoundaryPlot evaluates the classifier m over a grid covering the 2D
+// points in X and returns a plot of the regions assigned to each class
+// with the points, coloured by their labels y, drawn on top
+func BoundaryPlot[T constraints.Float](gc *grad.Context[T], m grad.Module[T], X [][]float64, y []int, opts BoundaryOptions) (*plot.Plot, error) {
+	opts = opts.withDefaults()
+
+	if len(X) < 1 || len(X) != len(y) {
+		return nil, fmt.Errorf("decision boundary: %d points and %d labels", len(X), len(y))
+	}
+
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	classes := 2
+	for i, x := range X {
+		if len(x) != 2 {
+			return nil, fmt.Errorf("decision boundary: point %d has %d dimensions, need 2", i, len(x))
+		}
+		if y[i] < 0 {
+			return nil, fmt.Errorf("decision boundary: point %d has label %d, labels are classes from 0", i, y[i])
+		}
+
+		minX, maxX = min(minX, x[0]), max(maxX, x[0])
+		minY, maxY = min(minY, x[1]), max(maxY, x[1])
+		classes = max(classes, y[i]+1)
+	}
+
+	padX := max(opts.Margin*(maxX-minX), 0.5)
+	padY := max(opts.Margin*(maxY-minY), 0.5)
+	minX, maxX = minX-padX, maxX+padX
+	minY, maxY = minY-padY, maxY+padY
+
+	// Evaluate the grid in evaluation mode so that dropout doesn't add
+	// noise and running statistics are left alone
+	if t, ok := m.(grad.Trainable); ok {
+		defer t.SetTraining(t.Training())
+		t.SetTraining(false)
+	}
+
+	n := opts.Resolution
+	g := grid{
+		xs: make([]float64, n),
+		ys: make([]float64, n),
+		z:  make([][]float64, n),
+	}
+	for i := range n {
+		g.xs[i] = minX + (maxX-minX)*float64(i)/float64(n-1)
+		g.ys[i] = minY + (maxY-minY)*float64(i)/float64(n-1)
+	}
+
+	for c := range n {
+		g.z[c] = make([]float64, n)
+		for r := range n {
+			out, err := grad.ForwardE(m, gc.Vals(T(g.xs[c]), T(g.ys[r])))
+			if err != nil {
+				return nil, fmt.Errorf("decision boundary: %w", err)
+			}
+
+			class := predictClass(out)
+			classes = max(classes, class+1)
+			g.z[c][r] = float64(class)
+		}
+	}
+
+	pal := make(classPalette, classes)
+	for i := range pal {
+		pal[i] = tint(classColor(i), 0.7)
+	}
+
+	heat := plotter.NewHeatMap(&g, pal)
+	heat.Min = 0
+	heat.Max = float64(classes - 1)
+	heat.Rasterized = true
+
+	p := plot.New()
+	p.Title.Text = opts.Title
+	p.X.Label.Text = "X"
+	p.Y.Label.Text = "Y"
+	p.Add(heat)
+
+	points := make([]plotter.XYs, classes)
+	for i, x := range X {
+		points[y[i]] = append(points[y[i]], plotter.XY{X: x[0], Y: x[1]})
+	}
+
+	for class, xys := range points {
+		if len(xys) < 1 {
+			continue
+		}
+
+		s, err := plotter.NewScatter(xys)
+		if err != nil {
+			return nil, fmt.Errorf("decision boundary: %w", err)
+		}
+		s.GlyphStyle.Color = classColor(class)
+		s.GlyphStyle.Shape = draw.CircleGlyph{}
+
+		p.Add(s)
+		p.Legend.Add(fmt.Sprintf("Class %d", class), s)
+	}
+
+	p.X.Min, p.X.Max = minX, maxX
+	p.Y.Min, p.Y.Max = minY, maxY
+
+	return p, nil
+}
+
+// DecisionBoundary saves a BoundaryPlot to path, the format is chosen
+// by the extension, e.g. .png, .svg or .pdf
+func DecisionBoundary[T constraints.Float](path string, gc *grad.Context[T], m grad.Module[T], X [][]float64, y []int, opts BoundaryOptions) error {
+	p, err := BoundaryPlot(gc, m, X, y, opts)
+	if err != nil {
+		return err
+	}
+
+	opts = opts.withDefaults()
+	if err := p.Save(opts.Width, opts.Height, path); err != nil {
+		return fmt.Errorf("Save %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// WriteBoundary draws a BoundaryPlot in format, such as png or svg
+func WriteBoundary[T constraints.Float](w io.Writer, format string, gc *grad.Context[T], m grad.Module[T], X [][]float64, y []int, opts BoundaryOptions) error {
+	p, err := BoundaryPlot(gc, m, X, y, opts)
+	if err != nil {
+		return err
+	}
+
+	opts = opts.withDefaults()
+	wt, err := p.WriterTo(opts.Width, opts.Height, format)
+	if err != nil {
+		return fmt.Errorf("decision boundary: %w", err)
+	}
+
+	_, err = wt.WriteTo(w)
+
+	return err
+}
@@ -0,0 +1,152 @@
+package viz
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+
+	"github.com/richiejp/micrograd/internal/train"
+)
+
+type series struct {
+	name  string
+	color color.Color
+	xys   plotter.XYs
+}
+
+func linePlot(title string, ss ...series) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = "Step"
+	p.Legend.Top = true
+	p.Add(plotter.NewGrid())
+
+	for _, s := range ss {
+		if len(s.xys) < 1 {
+			continue
+		}
+
+		l, err := plotter.NewLine(s.xys)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", title, err)
+		}
+		l.LineStyle.Color = s.color
+		l.LineStyle.Width = vg.Points(1.5)
+
+		p.Add(l)
+		if len(ss) > 1 {
+			p.Legend.Add(s.name, l)
+		}
+	}
+
+	return p, nil
+}
+
+// HistoryPlots returns line plots of the loss, accuracy, learning rate
+// and gradient norm at each step. The loss and accuracy plots include
+// the validation metrics if there are any.
+func HistoryPlots(h *train.History) ([]*plot.Plot, error) {
+	if len(h.Steps) < 1 {
+		return nil, fmt.Errorf("history plots: no steps")
+	}
+
+	trainColor := classColor(1)
+	valColor := classColor(0)
+
+	var loss, acc, valLoss, valAcc, lr, norm plotter.XYs
+	for _, s := range h.Steps {
+		x := float64(s.Step)
+
+		loss = append(loss, plotter.XY{X: x, Y: s.Train.Loss})
+		acc = append(acc, plotter.XY{X: x, Y: s.Train.Accuracy})
+		lr = append(lr, plotter.XY{X: x, Y: s.LearningRate})
+		norm = append(norm, plotter.XY{X: x, Y: s.GradNorm})
+
+		if s.Val != nil {
+			valLoss = append(valLoss, plotter.XY{X: x, Y: s.Val.Loss})
+			valAcc = append(valAcc, plotter.XY{X: x, Y: s.Val.Accuracy})
+		}
+	}
+
+	lossSeries := []series{{"Train", trainColor, loss}}
+	accSeries := []series{{"Train", trainColor, acc}}
+	if h.HasVal() {
+		lossSeries = append(lossSeries, series{"Validation", valColor, valLoss})
+		accSeries = append(accSeries, series{"Validation", valColor, valAcc})
+	}
+
+	var plots []*plot.Plot
+	for _, ps := range []struct {
+		title string
+		ss    []series
+	}{
+		{"Loss", lossSeries},
+		{"Accuracy", accSeries},
+		{"Learning rate", []series{{"Train", trainColor, lr}}},
+		{"Gradient norm", []series{{"Train", trainColor, norm}}},
+	} {
+		p, err := linePlot(ps.title, ps.ss...)
+		if err != nil {
+			return nil, err
+		}
+		plots = append(plots, p)
+	}
+
+	// Accuracy rises so its legend goes at the bottom out of the way
+	plots[1].Y.Min, plots[1].Y.Max = 0, 1
+	plots[1].Legend.Top = false
+
+	return plots, nil
+}
+
+// PlotHistory saves the HistoryPlots to path in a 2x2 grid, the format
+// is chosen by the extension, e.g. .png, .svg or .pdf
+func PlotHistory(path string, h *train.History) error {
+	plots, err := HistoryPlots(h)
+	if err != nil {
+		return err
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	c, err := draw.NewFormattedCanvas(10*vg.Inch, 8*vg.Inch, format)
+	if err != nil {
+		return fmt.Errorf("plot history: %w", err)
+	}
+
+	tiles := draw.Tiles{
+		Rows:      2,
+		Cols:      2,
+		PadX:      4 * vg.Millimeter,
+		PadY:      4 * vg.Millimeter,
+		PadTop:    2 * vg.Millimeter,
+		PadBottom: 2 * vg.Millimeter,
+		PadLeft:   2 * vg.Millimeter,
+		PadRight:  2 * vg.Millimeter,
+	}
+	grid := [][]*plot.Plot{plots[:2], plots[2:]}
+	canvases := plot.Align(grid, tiles, draw.New(c))
+	for i, row := range grid {
+		for j, p := range row {
+			p.Draw(canvases[i][j])
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := c.WriteTo(file); err != nil {
+		return fmt.Errorf("Write %s: %w", path, err)
+	}
+
+	return file.Close()
+}
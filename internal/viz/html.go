@@ -0,0 +1,188 @@
+package viz
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+	"gonum.org/v1/plot/vg"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+//go:embed explorer.html
+var explorerHTML string
+
+var explorerTmpl = template.Must(template.New("explorer").Parse(explorerHTML))
+
+var kindNames = map[nodeKind]string{
+	kindValue:   "value",
+	kindInput:   "input",
+	kindParam:   "param",
+	kindOp:      "op",
+	kindSummary: "summary",
+}
+
+// The types below are the graph given to the page as JSON, positions
+// are in SVG coordinates with y pointing down. Numbers are formatted as
+// strings because JSON can't represent NaN or infinity.
+
+type htmlNode struct {
+	ID      string   `json:"id"`
+	Kind    string   `json:"kind"`
+	Label   string   `json:"label"`
+	Op      string   `json:"op,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Data    string   `json:"data,omitempty"`
+	Grad    string   `json:"grad,omitempty"`
+	Cluster []string `json:"cluster,omitempty"`
+	Fields  []string `json:"fields"`
+	Fill    string   `json:"fill,omitempty"`
+	Stroke  string   `json:"stroke,omitempty"`
+	Pen     float64  `json:"pen,omitempty"`
+	X       float64  `json:"x"`
+	Y       float64  `json:"y"`
+	W       float64  `json:"w"`
+	H       float64  `json:"h"`
+	// Widths of each field
+	FieldW []float64 `json:"fieldW"`
+}
+
+type htmlEdge struct {
+	From   string       `json:"from"`
+	To     string       `json:"to"`
+	Points [][2]float64 `json:"points"`
+}
+
+type htmlCluster struct {
+	Name  string  `json:"name"`
+	Depth int     `json:"depth"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	W     float64 `json:"w"`
+	H     float64 `json:"h"`
+}
+
+type htmlGraph struct {
+	Width    float64       `json:"width"`
+	Height   float64       `json:"height"`
+	FontSize float64       `json:"fontSize"`
+	Nodes    []htmlNode    `json:"nodes"`
+	Edges    []htmlEdge    `json:"edges"`
+	Clusters []htmlCluster `json:"clusters"`
+}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+func writeHTML(w io.Writer, g *graphData, o *renderOptions, title string) error {
+	s := newScene(g, o)
+	off := s.offset()
+
+	// Flip from the canvas' y up to y down
+	point := func(pt vg.Point) [2]float64 {
+		return [2]float64{float64(pt.X + off.X), float64(s.height - pt.Y - off.Y)}
+	}
+
+	hg := htmlGraph{
+		Width:    float64(s.width),
+		Height:   float64(s.height),
+		FontSize: float64(fontSize),
+	}
+
+	for _, key := range s.keys {
+		b := s.boxes[key]
+		names := strings.Split(key, "\x00")
+		topLeft := point(vg.Point{X: b.Min.X, Y: b.Max.Y})
+
+		hg.Clusters = append(hg.Clusters, htmlCluster{
+			Name:  names[len(names)-1],
+			Depth: len(names) - 1,
+			X:     topLeft[0],
+			Y:     topLeft[1],
+			W:     float64(b.Max.X - b.Min.X),
+			H:     float64(b.Max.Y - b.Min.Y),
+		})
+	}
+
+	for _, n := range s.l.nodes {
+		if n.v == nil {
+			continue
+		}
+
+		v := n.v
+		st := v.style(o, s.p.maxGrad)
+		centre := point(n.centre)
+		hn := htmlNode{
+			ID:      v.id,
+			Kind:    kindNames[v.kind],
+			Label:   v.label,
+			Op:      string(v.op),
+			Name:    v.name,
+			Cluster: v.cluster,
+			Fields:  v.fields(),
+			Fill:    st.fill,
+			Stroke:  st.stroke,
+			Pen:     st.penwidth,
+			X:       centre[0],
+			Y:       centre[1],
+			W:       float64(n.w),
+			H:       float64(n.h),
+		}
+
+		if v.kind != kindOp && (v.summary == nil || v.summary.hasData) {
+			hn.Data = formatFloat(v.data)
+			hn.Grad = formatFloat(v.grad)
+		}
+
+		for _, f := range hn.Fields {
+			hn.FieldW = append(hn.FieldW, float64(s.p.face.Width(f)+2*padding))
+		}
+
+		hg.Nodes = append(hg.Nodes, hn)
+	}
+
+	for _, path := range s.l.paths {
+		he := htmlEdge{
+			From: s.l.nodes[path[0]].v.id,
+			To:   s.l.nodes[path[len(path)-1]].v.id,
+		}
+		for _, pt := range s.l.edgePoints(path) {
+			he.Points = append(he.Points, point(pt))
+		}
+
+		hg.Edges = append(hg.Edges, he)
+	}
+
+	return explorerTmpl.Execute(w, struct {
+		Title string
+		Graph htmlGraph
+	}{
+		Title: title,
+		Graph: hg,
+	})
+}
+
+// WriteHTML writes a web page, with no external dependencies, for
+// exploring the graph reaching v. It can be panned by dragging, zoomed
+// with the mouse wheel and searched by label or name. Clicking a node
+// shows its details.
+func WriteHTML[T constraints.Float](w io.Writer, v *grad.Value[T], opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+
+	g, err := build(v, o)
+	if err != nil {
+		return err
+	}
+
+	title := "Computation graph"
+	if v.Label() != "" {
+		title += " of " + v.Label()
+	}
+
+	return writeHTML(w, g, o, title)
+}
@@ -0,0 +1,362 @@
+package viz
+
+import (
+	"image/color"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/font"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+const (
+	fontSize        = vg.Length(10)
+	clusterFontSize = vg.Length(8)
+	// padding is the space around text in a node
+	padding = vg.Length(4)
+	// margin is the space around the whole graph
+	margin = vg.Length(16)
+	// clusterPad is the space between a cluster's box and its contents
+	clusterPad = vg.Length(5)
+	// maxRasterSize limits the size of PNG and other raster images, larger
+	// graphs are scaled down to fit
+	maxRasterSize = vg.Length(4000)
+)
+
+// vectorFormats can be any size
+var vectorFormats = []string{"svg", "pdf", "eps", "tex"}
+
+// imageFormats are those WriteImage can draw
+var imageFormats = append([]string{"png", "jpg", "jpeg", "tif", "tiff"}, vectorFormats...)
+
+// parseColor converts a "#rrggbb" string to a colour
+func parseColor(s string) color.Color {
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil {
+		return color.Black
+	}
+
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}
+}
+
+type painter struct {
+	c        vg.Canvas
+	face     font.Face
+	small    font.Face
+	o        *renderOptions
+	maxGrad  float64
+	textSize vg.Length
+}
+
+func newPainter(o *renderOptions, maxGrad float64) *painter {
+	p := &painter{
+		face:    font.DefaultCache.Lookup(plot.DefaultFont, fontSize),
+		small:   font.DefaultCache.Lookup(plot.DefaultFont, clusterFontSize),
+		o:       o,
+		maxGrad: maxGrad,
+	}
+	ext := p.face.Extents()
+	p.textSize = ext.Ascent + ext.Descent
+
+	return p
+}
+
+func (p *painter) measure(v *vertex) (vg.Length, vg.Length) {
+	h := p.textSize + 2*padding
+
+	var w vg.Length
+	for _, f := range v.fields() {
+		w += p.face.Width(f) + 2*padding
+	}
+
+	// The ends of op nodes are round
+	if v.kind == kindOp {
+		w += h / 2
+	}
+
+	return w, h
+}
+
+// text draws s centred on pt
+func (p *painter) text(face font.Face, pt vg.Point, s string) {
+	ext := face.Extents()
+	p.c.FillString(face, vg.Point{
+		X: pt.X - face.Width(s)/2,
+		Y: pt.Y - (ext.Ascent-ext.Descent)/2,
+	}, s)
+}
+
+func (p *painter) rect(r vg.Rectangle) vg.Path {
+	var path vg.Path
+	path.Move(r.Min)
+	path.Line(vg.Point{X: r.Max.X, Y: r.Min.Y})
+	path.Line(r.Max)
+	path.Line(vg.Point{X: r.Min.X, Y: r.Max.Y})
+	path.Close()
+
+	return path
+}
+
+// pill is a rectangle with semicircular ends
+func (p *painter) pill(r vg.Rectangle) vg.Path {
+	rad := (r.Max.Y - r.Min.Y) / 2
+	var path vg.Path
+	path.Move(vg.Point{X: r.Min.X + rad, Y: r.Min.Y})
+	path.Line(vg.Point{X: r.Max.X - rad, Y: r.Min.Y})
+	path.Arc(vg.Point{X: r.Max.X - rad, Y: r.Min.Y + rad}, rad, -math.Pi/2, math.Pi)
+	path.Line(vg.Point{X: r.Min.X + rad, Y: r.Max.Y})
+	path.Arc(vg.Point{X: r.Min.X + rad, Y: r.Min.Y + rad}, rad, math.Pi/2, math.Pi)
+	path.Close()
+
+	return path
+}
+
+func (p *painter) node(n *lnode) {
+	v := n.v
+	st := v.style(p.o, p.maxGrad)
+	r := vg.Rectangle{
+		Min: vg.Point{X: n.centre.X - n.w/2, Y: n.centre.Y - n.h/2},
+		Max: vg.Point{X: n.centre.X + n.w/2, Y: n.centre.Y + n.h/2},
+	}
+
+	shape := p.rect(r)
+	if v.kind == kindOp {
+		shape = p.pill(r)
+	}
+
+	fill := "#ffffff"
+	if st.fill != "" {
+		fill = st.fill
+	}
+	p.c.SetColor(parseColor(fill))
+	p.c.Fill(shape)
+
+	p.c.SetColor(color.Black)
+	if st.stroke != "" {
+		p.c.SetColor(parseColor(st.stroke))
+	}
+	p.c.SetLineWidth(vg.Points(max(st.penwidth, 1)))
+	p.c.Stroke(shape)
+
+	fields := v.fields()
+	if v.kind == kindOp {
+		p.c.SetColor(color.Black)
+		p.text(p.face, n.centre, fields[0])
+		return
+	}
+
+	x := r.Min.X
+	for i, f := range fields {
+		w := p.face.Width(f) + 2*padding
+
+		if i > 0 {
+			var sep vg.Path
+			sep.Move(vg.Point{X: x, Y: r.Min.Y})
+			sep.Line(vg.Point{X: x, Y: r.Max.Y})
+			p.c.SetLineWidth(vg.Points(1))
+			p.c.Stroke(sep)
+		}
+
+		p.c.SetColor(color.Black)
+		p.text(p.face, vg.Point{X: x + w/2, Y: n.centre.Y}, f)
+		x += w
+	}
+}
+
+func (p *painter) edge(pts []vg.Point) {
+	p.c.SetColor(color.Gray{Y: 60})
+	p.c.SetLineWidth(vg.Points(1))
+
+	var line vg.Path
+	line.Move(pts[0])
+	for _, pt := range pts[1:] {
+		line.Line(pt)
+	}
+	p.c.Stroke(line)
+
+	// Arrow head along the last segment
+	end, prev := pts[len(pts)-1], pts[len(pts)-2]
+	dx, dy := float64(end.X-prev.X), float64(end.Y-prev.Y)
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		return
+	}
+	dx, dy = dx/d, dy/d
+
+	const size = 6
+	back := vg.Point{X: end.X - vg.Length(dx*size), Y: end.Y - vg.Length(dy*size)}
+	var head vg.Path
+	head.Move(end)
+	head.Line(vg.Point{X: back.X - vg.Length(dy*size/2), Y: back.Y + vg.Length(dx*size/2)})
+	head.Line(vg.Point{X: back.X + vg.Length(dy*size/2), Y: back.Y - vg.Length(dx*size/2)})
+	head.Close()
+	p.c.Fill(head)
+}
+
+// clusterBoxes returns the bounding box of each cluster path, outer
+// clusters are larger so that nested boxes don't touch
+func (p *painter) clusterBoxes(l *layout) (map[string]vg.Rectangle, []string) {
+	depth := 0
+	for _, n := range l.nodes {
+		if n.v != nil {
+			depth = max(depth, len(n.v.cluster))
+		}
+	}
+
+	boxes := make(map[string]vg.Rectangle)
+	var keys []string
+	for _, n := range l.nodes {
+		if n.v == nil {
+			continue
+		}
+
+		key := ""
+		for d, name := range n.v.cluster {
+			key += "\x00" + name
+			pad := clusterPad * vg.Length(depth-d)
+			top := (clusterFontSize + clusterPad) * vg.Length(depth-d)
+			r := vg.Rectangle{
+				Min: vg.Point{X: n.centre.X - n.w/2 - pad, Y: n.centre.Y - n.h/2 - pad},
+				Max: vg.Point{X: n.centre.X + n.w/2 + pad, Y: n.centre.Y + n.h/2 + top},
+			}
+
+			if b, ok := boxes[key]; ok {
+				r = vg.Rectangle{
+					Min: vg.Point{X: min(b.Min.X, r.Min.X), Y: min(b.Min.Y, r.Min.Y)},
+					Max: vg.Point{X: max(b.Max.X, r.Max.X), Y: max(b.Max.Y, r.Max.Y)},
+				}
+			} else {
+				keys = append(keys, key)
+			}
+			boxes[key] = r
+		}
+	}
+
+	return boxes, keys
+}
+
+func (p *painter) cluster(r vg.Rectangle, key string) {
+	names := strings.Split(key, "\x00")
+	depth := len(names) - 1
+
+	shade := uint8(245 - 8*min(depth-1, 4))
+	p.c.SetColor(color.Gray{Y: shade})
+	p.c.Fill(p.rect(r))
+
+	p.c.SetColor(color.Gray{Y: 150})
+	p.c.SetLineWidth(vg.Points(1))
+	p.c.Stroke(p.rect(r))
+
+	p.c.SetColor(color.Gray{Y: 80})
+	p.c.FillString(p.small, vg.Point{
+		X: r.Min.X + clusterPad,
+		Y: r.Max.Y - clusterFontSize,
+	}, names[len(names)-1])
+}
+
+func (p *painter) draw(l *layout, boxes map[string]vg.Rectangle, keys []string) {
+	// Parents come before their children so they are drawn underneath
+	for _, key := range keys {
+		p.cluster(boxes[key], key)
+	}
+
+	for _, path := range l.paths {
+		p.edge(l.edgePoints(path))
+	}
+
+	for _, n := range l.nodes {
+		if n.v != nil {
+			p.node(n)
+		}
+	}
+}
+
+// scene is a laid out graph ready to draw
+type scene struct {
+	p      *painter
+	l      *layout
+	boxes  map[string]vg.Rectangle
+	keys   []string
+	bounds vg.Rectangle
+	width  vg.Length
+	height vg.Length
+}
+
+func newScene(g *graphData, o *renderOptions) *scene {
+	p := newPainter(o, g.maxGrad())
+	l := newLayout(g, o.direction, p.measure)
+
+	// Clusters may stick out beyond the nodes
+	boxes, keys := p.clusterBoxes(l)
+	bounds := vg.Rectangle{Max: vg.Point{X: l.width, Y: l.height}}
+	for _, b := range boxes {
+		bounds.Min.X, bounds.Min.Y = min(bounds.Min.X, b.Min.X), min(bounds.Min.Y, b.Min.Y)
+		bounds.Max.X, bounds.Max.Y = max(bounds.Max.X, b.Max.X), max(bounds.Max.Y, b.Max.Y)
+	}
+
+	return &scene{
+		p:      p,
+		l:      l,
+		boxes:  boxes,
+		keys:   keys,
+		bounds: bounds,
+		width:  bounds.Max.X - bounds.Min.X + 2*margin,
+		height: bounds.Max.Y - bounds.Min.Y + 2*margin,
+	}
+}
+
+// offset moves a point in the layout into the margin of the scene
+func (s *scene) offset() vg.Point {
+	return vg.Point{X: margin - s.bounds.Min.X, Y: margin - s.bounds.Min.Y}
+}
+
+func writeImage(w io.Writer, format string, g *graphData, o *renderOptions) error {
+	s := newScene(g, o)
+
+	scale := 1.0
+	if !slices.Contains(vectorFormats, format) {
+		scale = min(1, float64(maxRasterSize/max(s.width, s.height)))
+	}
+
+	c, err := draw.NewFormattedCanvas(s.width*vg.Length(scale), s.height*vg.Length(scale), format)
+	if err != nil {
+		return err
+	}
+
+	c.Push()
+	c.Scale(scale, scale)
+
+	p := s.p
+	p.c = c
+	p.c.SetColor(color.White)
+	p.c.Fill(p.rect(vg.Rectangle{Max: vg.Point{X: s.width, Y: s.height}}))
+
+	c.Translate(s.offset())
+	p.draw(s.l, s.boxes, s.keys)
+	c.Pop()
+
+	_, err = c.WriteTo(w)
+
+	return err
+}
+
+// WriteImage lays out the graph reaching v and draws it in format, which
+// is one of png, jpg, tif, svg, pdf, eps or tex. Graphviz is not needed.
+func WriteImage[T constraints.Float](w io.Writer, format string, v *grad.Value[T], opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+
+	g, err := build(v, o)
+	if err != nil {
+		return err
+	}
+
+	return writeImage(w, strings.ToLower(format), g, o)
+}
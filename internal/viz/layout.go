@@ -0,0 +1,485 @@
+package viz
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"gonum.org/v1/plot/vg"
+)
+
+const (
+	// nodeGap separates nodes in the same layer
+	nodeGap = vg.Length(12)
+	// layerGap separates the layers
+	layerGap = vg.Length(36)
+	// sweeps is the number of passes made to reduce edge crossings
+	sweeps = 8
+)
+
+// lnode is a vertex being laid out, or a dummy node which carries an
+// edge across a layer
+type lnode struct {
+	v *vertex
+	// w and h are the drawn width and height
+	w, h vg.Length
+	// cluster orders nodes so those in the same cluster are adjacent
+	cluster []int
+	layer   int
+	pred    []int
+	succ    []int
+	// pos is the centre across the layer
+	pos vg.Length
+	// centre is the final position
+	centre vg.Point
+}
+
+// layout places the vertices of a graph in layers so that every edge
+// points in the same direction. It follows the usual steps of a
+// Sugiyama style layout: longest path layering, dummy nodes for long
+// edges, barycentre ordering to reduce crossings and finally pulling
+// nodes towards their neighbours.
+type layout struct {
+	dir   Direction
+	nodes []*lnode
+	// paths are the edges as node indices from source to target,
+	// including any dummy nodes
+	paths  [][]int
+	layers [][]int
+	width  vg.Length
+	height vg.Length
+}
+
+// along is the size of a node in the direction the graph flows and
+// across is its size in the layer
+func (l *layout) along(n *lnode) vg.Length {
+	if l.dir == LeftToRight || l.dir == RightToLeft {
+		return n.w
+	}
+	return n.h
+}
+
+func (l *layout) across(n *lnode) vg.Length {
+	if l.dir == LeftToRight || l.dir == RightToLeft {
+		return n.h
+	}
+	return n.w
+}
+
+func newLayout(g *graphData, dir Direction, measure func(v *vertex) (vg.Length, vg.Length)) *layout {
+	l := &layout{dir: dir}
+	idx := make(map[string]int, len(g.vertices))
+
+	// Number the clusters at each level in the order they appear
+	clusterIDs := make(map[string]int)
+	for i, v := range g.vertices {
+		n := &lnode{v: v}
+		n.w, n.h = measure(v)
+
+		key := ""
+		for _, name := range v.cluster {
+			key += "\x00" + name
+			if _, ok := clusterIDs[key]; !ok {
+				clusterIDs[key] = len(clusterIDs)
+			}
+			n.cluster = append(n.cluster, clusterIDs[key])
+		}
+
+		l.nodes = append(l.nodes, n)
+		idx[v.id] = i
+	}
+
+	seen := make(map[[2]int]bool)
+	for _, e := range g.edges {
+		pair := [2]int{idx[e.From], idx[e.To]}
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		l.paths = append(l.paths, pair[:])
+		l.nodes[pair[0]].succ = append(l.nodes[pair[0]].succ, pair[1])
+		l.nodes[pair[1]].pred = append(l.nodes[pair[1]].pred, pair[0])
+	}
+
+	l.assignLayers()
+	l.addDummies()
+	l.order()
+	l.place()
+
+	return l
+}
+
+// topoOrder returns the nodes in an order where each comes after its
+// predecessors
+func (l *layout) topoOrder() []int {
+	indeg := make([]int, len(l.nodes))
+	for _, n := range l.nodes {
+		for _, s := range n.succ {
+			indeg[s]++
+		}
+	}
+
+	var queue []int
+	for i, d := range indeg {
+		if d == 0 {
+			queue = append(queue, i)
+		}
+	}
+
+	order := make([]int, 0, len(l.nodes))
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		order = append(order, i)
+
+		for _, s := range l.nodes[i].succ {
+			indeg[s]--
+			if indeg[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+
+	return order
+}
+
+// assignLayers puts each node one layer after its furthest predecessor
+// then moves the inputs and parameters next to where they are first used
+func (l *layout) assignLayers() {
+	order := l.topoOrder()
+
+	for _, i := range order {
+		n := l.nodes[i]
+		for _, s := range n.succ {
+			l.nodes[s].layer = max(l.nodes[s].layer, n.layer+1)
+		}
+	}
+
+	for _, i := range slices.Backward(order) {
+		n := l.nodes[i]
+		if len(n.pred) > 0 || len(n.succ) < 1 {
+			continue
+		}
+
+		first := l.nodes[n.succ[0]].layer
+		for _, s := range n.succ[1:] {
+			first = min(first, l.nodes[s].layer)
+		}
+		n.layer = first - 1
+	}
+}
+
+// addDummies splits edges which span more than one layer so that every
+// edge goes between adjacent layers
+func (l *layout) addDummies() {
+	for p, path := range l.paths {
+		from, to := l.nodes[path[0]], l.nodes[path[1]]
+		if to.layer-from.layer < 2 {
+			continue
+		}
+
+		from.succ = slices.DeleteFunc(from.succ, func(s int) bool { return s == path[1] })
+		to.pred = slices.DeleteFunc(to.pred, func(s int) bool { return s == path[0] })
+
+		prev := path[0]
+		long := []int{prev}
+		for layer := from.layer + 1; layer < to.layer; layer++ {
+			d := len(l.nodes)
+			l.nodes = append(l.nodes, &lnode{
+				w:       1,
+				h:       1,
+				cluster: from.cluster,
+				layer:   layer,
+				pred:    []int{prev},
+			})
+			l.nodes[prev].succ = append(l.nodes[prev].succ, d)
+			long = append(long, d)
+			prev = d
+		}
+
+		l.nodes[prev].succ = append(l.nodes[prev].succ, path[1])
+		to.pred = append(to.pred, prev)
+		l.paths[p] = append(long, path[1])
+	}
+}
+
+// order arranges the nodes within each layer to reduce crossings by
+// repeatedly sorting them by the average position of their neighbours
+func (l *layout) order() {
+	minLayer, maxLayer := 0, 0
+	for _, n := range l.nodes {
+		minLayer = min(minLayer, n.layer)
+		maxLayer = max(maxLayer, n.layer)
+	}
+
+	l.layers = make([][]int, maxLayer-minLayer+1)
+	for i, n := range l.nodes {
+		n.layer -= minLayer
+		l.layers[n.layer] = append(l.layers[n.layer], i)
+	}
+
+	rank := make([]float64, len(l.nodes))
+	setRanks := func(layer []int) {
+		for r, i := range layer {
+			rank[i] = float64(r)
+		}
+	}
+	for _, layer := range l.layers {
+		setRanks(layer)
+	}
+
+	bary := make([]float64, len(l.nodes))
+	for s := range sweeps {
+		down := s%2 == 0
+
+		for k := range l.layers {
+			if !down {
+				k = len(l.layers) - 1 - k
+			}
+			layer := l.layers[k]
+
+			for _, i := range layer {
+				n := l.nodes[i]
+				neighbours := n.pred
+				if !down {
+					neighbours = n.succ
+				}
+
+				bary[i] = rank[i]
+				if len(neighbours) > 0 {
+					sum := 0.0
+					for _, j := range neighbours {
+						sum += rank[j]
+					}
+					bary[i] = sum / float64(len(neighbours))
+				}
+			}
+
+			slices.SortStableFunc(layer, func(a, b int) int {
+				if c := slices.Compare(l.nodes[a].cluster, l.nodes[b].cluster); c != 0 {
+					return c
+				}
+				return cmp.Compare(bary[a], bary[b])
+			})
+			setRanks(layer)
+		}
+	}
+}
+
+// pack moves the nodes of a layer as little as possible from want so
+// that they don't overlap, keeping their order
+func (l *layout) pack(layer []int, want []vg.Length) {
+	n := len(layer)
+	fwd := make([]vg.Length, n)
+	bwd := make([]vg.Length, n)
+
+	for k, i := range layer {
+		fwd[k] = want[k]
+		if k > 0 {
+			gap := (l.across(l.nodes[layer[k-1]])+l.across(l.nodes[i]))/2 + nodeGap
+			fwd[k] = max(fwd[k], fwd[k-1]+gap)
+		}
+	}
+
+	for k := n - 1; k >= 0; k-- {
+		bwd[k] = want[k]
+		if k < n-1 {
+			gap := (l.across(l.nodes[layer[k+1]])+l.across(l.nodes[layer[k]]))/2 + nodeGap
+			bwd[k] = min(bwd[k], bwd[k+1]-gap)
+		}
+	}
+
+	for k, i := range layer {
+		l.nodes[i].pos = (fwd[k] + bwd[k]) / 2
+		if k > 0 {
+			prev := l.nodes[layer[k-1]]
+			gap := (l.across(prev)+l.across(l.nodes[i]))/2 + nodeGap
+			l.nodes[i].pos = max(l.nodes[i].pos, prev.pos+gap)
+		}
+	}
+}
+
+// placeBands gives each cluster a band across every layer, wide enough
+// for its nodes in any layer, so that the boxes drawn around clusters
+// don't overlap
+func (l *layout) placeBands(depth int) {
+	var keys [][]int
+	extents := make(map[string][]vg.Length)
+	keyOf := func(n *lnode) string {
+		return fmt.Sprint(n.cluster)
+	}
+
+	for _, n := range l.nodes {
+		k := keyOf(n)
+		if _, ok := extents[k]; !ok {
+			keys = append(keys, n.cluster)
+			extents[k] = make([]vg.Length, len(l.layers))
+		}
+		extents[k][n.layer] += l.across(n) + nodeGap
+	}
+	slices.SortFunc(keys, slices.Compare)
+
+	// Room for the padding and labels of the nested cluster boxes
+	bandGap := nodeGap + vg.Length(depth)*(3*clusterPad+clusterFontSize)
+
+	starts := make(map[string]vg.Length)
+	var start vg.Length
+	for _, key := range keys {
+		k := fmt.Sprint(key)
+		starts[k] = start
+		start += slices.Max(extents[k]) + bandGap
+	}
+
+	for _, layer := range l.layers {
+		used := make(map[string]vg.Length)
+		for _, i := range layer {
+			n := l.nodes[i]
+			k := keyOf(n)
+			width := slices.Max(extents[k])
+			offset := (width - extents[k][n.layer]) / 2
+
+			n.pos = starts[k] + offset + used[k] + l.across(n)/2
+			used[k] += l.across(n) + nodeGap
+		}
+	}
+}
+
+// place gives each node its final position
+func (l *layout) place() {
+	depth := 0
+	for _, n := range l.nodes {
+		depth = max(depth, len(n.cluster))
+	}
+
+	if depth > 0 {
+		l.placeBands(depth)
+	} else {
+		l.pull()
+	}
+
+	l.finish()
+}
+
+// pull moves nodes towards the average position of their neighbours
+func (l *layout) pull() {
+	for _, layer := range l.layers {
+		l.pack(layer, make([]vg.Length, len(layer)))
+	}
+
+	for s := range sweeps {
+		down := s%2 == 0
+
+		for k := range l.layers {
+			if !down {
+				k = len(l.layers) - 1 - k
+			}
+			layer := l.layers[k]
+
+			want := make([]vg.Length, len(layer))
+			for r, i := range layer {
+				n := l.nodes[i]
+				neighbours := slices.Concat(n.pred, n.succ)
+
+				want[r] = n.pos
+				if len(neighbours) > 0 {
+					var sum vg.Length
+					for _, j := range neighbours {
+						sum += l.nodes[j].pos
+					}
+					want[r] = sum / vg.Length(len(neighbours))
+				}
+			}
+
+			l.pack(layer, want)
+		}
+	}
+}
+
+// finish converts the positions in and across the layers to points
+func (l *layout) finish() {
+	// Shift everything so the smallest edge is at zero
+	var lo, hi vg.Length
+	for i, n := range l.nodes {
+		if i == 0 || n.pos-l.across(n)/2 < lo {
+			lo = n.pos - l.across(n)/2
+		}
+		if i == 0 || n.pos+l.across(n)/2 > hi {
+			hi = n.pos + l.across(n)/2
+		}
+	}
+
+	thickness := make([]vg.Length, len(l.layers))
+	for k, layer := range l.layers {
+		for _, i := range layer {
+			thickness[k] = max(thickness[k], l.along(l.nodes[i]))
+		}
+	}
+
+	var length vg.Length
+	centres := make([]vg.Length, len(l.layers))
+	for k, t := range thickness {
+		centres[k] = length + t/2
+		length += t
+		if k < len(thickness)-1 {
+			length += layerGap
+		}
+	}
+
+	breadth := hi - lo
+	for _, n := range l.nodes {
+		across := n.pos - lo
+		along := centres[n.layer]
+
+		switch l.dir {
+		case LeftToRight:
+			n.centre = vg.Point{X: along, Y: breadth - across}
+		case RightToLeft:
+			n.centre = vg.Point{X: length - along, Y: breadth - across}
+		case BottomToTop:
+			n.centre = vg.Point{X: across, Y: along}
+		default:
+			n.centre = vg.Point{X: across, Y: length - along}
+		}
+	}
+
+	if l.dir == LeftToRight || l.dir == RightToLeft {
+		l.width, l.height = length, breadth
+	} else {
+		l.width, l.height = breadth, length
+	}
+}
+
+// flow is the unit vector of the direction edges point in
+func (l *layout) flow() vg.Point {
+	switch l.dir {
+	case LeftToRight:
+		return vg.Point{X: 1}
+	case RightToLeft:
+		return vg.Point{X: -1}
+	case BottomToTop:
+		return vg.Point{Y: 1}
+	default:
+		return vg.Point{Y: -1}
+	}
+}
+
+// edgePoints returns the polyline of a path from the side of the source
+// facing the target to the opposite side of the target
+func (l *layout) edgePoints(path []int) []vg.Point {
+	f := l.flow()
+	side := func(n *lnode, sign vg.Length) vg.Point {
+		return vg.Point{
+			X: n.centre.X + sign*f.X*n.w/2,
+			Y: n.centre.Y + sign*f.Y*n.h/2,
+		}
+	}
+
+	pts := make([]vg.Point, 0, len(path))
+	pts = append(pts, side(l.nodes[path[0]], 1))
+	for _, i := range path[1 : len(path)-1] {
+		pts = append(pts, l.nodes[i].centre)
+	}
+	pts = append(pts, side(l.nodes[path[len(path)-1]], -1))
+
+	return pts
+}
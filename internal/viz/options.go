@@ -0,0 +1,142 @@
+package viz
+
+import (
+	"golang.org/x/exp/constraints"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+// Direction is the direction in which the graph flows from the inputs
+// to the root, it is the DOT rankdir attribute
+type Direction string
+
+const (
+	TopToBottom Direction = "TB"
+	LeftToRight Direction = "LR"
+	BottomToTop Direction = "BT"
+	RightToLeft Direction = "RL"
+)
+
+// Clustering says how nodes are grouped, see WithClusters
+type Clustering int
+
+const (
+	ClusterNone Clustering = iota
+	// ClusterLayer groups nodes by layer
+	ClusterLayer
+	// ClusterNeuron groups nodes by neuron within each layer
+	ClusterNeuron
+)
+
+type renderOptions struct {
+	direction  Direction
+	clustering Clustering
+	// names of the parameters by value ID
+	names           map[uint64]string
+	opColors        bool
+	highlightParams bool
+	gradColors      bool
+	collapse        Clustering
+	maxDepth        int
+	// reaching is the ID of the value the graph is restricted to paths from
+	reaching *uint64
+}
+
+type RenderOption func(*renderOptions)
+
+func newRenderOptions(opts []RenderOption) *renderOptions {
+	o := &renderOptions{
+		direction: TopToBottom,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+func WithDirection(d Direction) RenderOption {
+	return func(o *renderOptions) {
+		o.direction = d
+	}
+}
+
+// WithModule names the parameters of m in the graph so that they can be
+// clustered and highlighted
+func WithModule[T constraints.Float](m grad.Module[T]) RenderOption {
+	names := make(map[uint64]string)
+	for name, p := range m.NamedParameters() {
+		names[p.ID()] = name
+	}
+
+	return func(o *renderOptions) {
+		if o.names == nil {
+			o.names = make(map[uint64]string)
+		}
+
+		for id, name := range names {
+			o.names[id] = name
+		}
+	}
+}
+
+// WithClusters groups the nodes belonging to each layer or neuron of the
+// module given to WithModule. A parameter belongs to the layer and
+// neuron in its name. Other nodes belong where their parameter inputs
+// do, or where all of their inputs do.
+func WithClusters(c Clustering) RenderOption {
+	return func(o *renderOptions) {
+		o.clustering = c
+	}
+}
+
+// WithOpColors fills op nodes with a colour for each type of op
+func WithOpColors() RenderOption {
+	return func(o *renderOptions) {
+		o.opColors = true
+	}
+}
+
+// WithParamHighlight outlines parameters and inputs in different
+// colours. Without WithModule every leaf is treated as an input.
+func WithParamHighlight() RenderOption {
+	return func(o *renderOptions) {
+		o.highlightParams = true
+	}
+}
+
+// WithGradColors fills value nodes from white to red by the magnitude of
+// their gradient relative to the largest in the graph
+func WithGradColors() RenderOption {
+	return func(o *renderOptions) {
+		o.gradColors = true
+	}
+}
+
+// WithCollapse replaces the nodes of each layer or neuron of the module
+// given to WithModule with a single summary node. Clusters are found as
+// they are by WithClusters.
+func WithCollapse(c Clustering) RenderOption {
+	return func(o *renderOptions) {
+		o.collapse = c
+	}
+}
+
+// WithMaxDepth leaves out values which are more than depth ops away from
+// the root, zero means no limit
+func WithMaxDepth(depth int) RenderOption {
+	return func(o *renderOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// WithReaching keeps only the nodes on a path from p to the root, such
+// as the computations a parameter takes part in
+func WithReaching[T constraints.Float](p *grad.Value[T]) RenderOption {
+	id := p.ID()
+
+	return func(o *renderOptions) {
+		o.reaching = &id
+	}
+}
@@ -0,0 +1,142 @@
+package viz
+
+import (
+	"fmt"
+	"strings"
+)
+
+const summaryColor = "#fff2cc"
+
+// summary describes the nodes replaced by a summary vertex
+type summary struct {
+	values int
+	params int
+	// hasData is set when a single value leaves the group, its data and
+	// grad are those of the summary
+	hasData bool
+}
+
+// clusterDepth is the length of the cluster paths at a level
+func clusterDepth(c Clustering) int {
+	switch c {
+	case ClusterLayer:
+		return 1
+	case ClusterNeuron:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func truncate(path []string, n int) []string {
+	if n < 1 || len(path) < 1 {
+		return nil
+	}
+
+	return path[:min(n, len(path))]
+}
+
+// collapse replaces the vertices of each cluster at level with a single
+// summary vertex, which is itself clustered at the display level
+func (g *graphData) collapse(level, display Clustering) {
+	depth := clusterDepth(level)
+
+	groups := make(map[string]*vertex)
+	byKey := make(map[string]*vertex)
+	var vertices []*vertex
+
+	for _, v := range g.vertices {
+		if len(v.cluster) < 1 {
+			vertices = append(vertices, v)
+			continue
+		}
+
+		path := truncate(v.cluster, depth)
+		key := strings.Join(path, "\x00")
+		s, ok := byKey[key]
+		if !ok {
+			s = &vertex{
+				id:      fmt.Sprintf("s%d", len(byKey)),
+				kind:    kindSummary,
+				label:   path[len(path)-1],
+				cluster: truncate(path, min(clusterDepth(display), len(path)-1)),
+				summary: &summary{},
+			}
+			byKey[key] = s
+			vertices = append(vertices, s)
+		}
+		groups[v.id] = s
+
+		switch v.kind {
+		case kindOp:
+		case kindParam:
+			s.summary.params++
+			s.summary.values++
+		default:
+			s.summary.values++
+		}
+	}
+
+	// Values used outside of their group, or not used at all, are its
+	// outputs
+	byID := make(map[string]*vertex)
+	for _, v := range g.vertices {
+		byID[v.id] = v
+	}
+
+	used := make(map[string]bool)
+	outputs := make(map[*vertex][]*vertex)
+	seen := make(map[vertexEdge]bool)
+	var edges []vertexEdge
+
+	for _, e := range g.edges {
+		used[e.From] = true
+
+		from, to := e.From, e.To
+		if s, ok := groups[from]; ok {
+			from = s.id
+		}
+		if s, ok := groups[to]; ok {
+			to = s.id
+		}
+
+		if from == to {
+			continue
+		}
+
+		if s, ok := groups[e.From]; ok {
+			outputs[s] = append(outputs[s], byID[e.From])
+		}
+
+		ce := vertexEdge{From: from, To: to}
+		if !seen[ce] {
+			seen[ce] = true
+			edges = append(edges, ce)
+		}
+	}
+
+	for _, v := range g.vertices {
+		if s, ok := groups[v.id]; ok && !used[v.id] {
+			outputs[s] = append(outputs[s], v)
+		}
+	}
+
+	for s, outs := range outputs {
+		if len(outs) != 1 {
+			unique := make(map[*vertex]bool)
+			for _, out := range outs {
+				unique[out] = true
+			}
+			if len(unique) != 1 {
+				continue
+			}
+		}
+
+		s.data = outs[0].data
+		s.grad = outs[0].grad
+		s.summary.hasData = true
+	}
+
+	g.vertices = vertices
+	g.edges = edges
+}
@@ -1,8 +1,15 @@
 package viz
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/dominikbraun/graph"
 	"github.com/dominikbraun/graph/draw"
@@ -11,65 +18,532 @@ import (
 	"github.com/richiejp/micrograd/internal/grad"
 )
 
-const opOffset = 10000
-
 type edge struct {
 	From uint64
-	To uint64
+	To   uint64
+}
+
+// valueID and opID give values and the ops which produced them separate
+// vertex IDs
+func valueID(id uint64) string {
+	return fmt.Sprintf("v%d", id)
+}
+
+func opID(id uint64) string {
+	return fmt.Sprintf("op%d", id)
+}
+
+type vertexEdge struct {
+	From string
+	To   string
+}
+
+type nodeKind int
+
+const (
+	// kindValue is the result of an op
+	kindValue nodeKind = iota
+	kindInput
+	kindParam
+	kindOp
+	// kindSummary stands for a collapsed layer or neuron
+	kindSummary
+)
+
+// vertex is a node to draw, either a value or the op which produced it
+type vertex struct {
+	id    string
+	kind  nodeKind
+	label string
+	op    grad.Op
+	data  float64
+	grad  float64
+	// name of a parameter
+	name string
+	// cluster is the path of nested clusters the vertex is drawn in
+	cluster []string
+	summary *summary
+}
+
+// fields is the text of each part of a node
+func (v *vertex) fields() []string {
+	switch v.kind {
+	case kindOp:
+		return []string{v.label}
+	case kindSummary:
+		fs := []string{v.label, fmt.Sprintf("%d values, %d params", v.summary.values, v.summary.params)}
+		if v.summary.hasData {
+			fs = append(fs, fmt.Sprintf("data %.4f", v.data), fmt.Sprintf("grad %.4f", v.grad))
+		}
+		return fs
+	}
+
+	return []string{v.label, fmt.Sprintf("data %.4f", v.data), fmt.Sprintf("grad %.4f", v.grad)}
 }
 
-func uint64Hash(x uint64) uint64 {
-	return x
+type graphData struct {
+	vertices []*vertex
+	edges    []vertexEdge
 }
 
-func trace[T constraints.Float](root *grad.Value[T]) (map[uint64]*grad.Value[T], []edge) {
-	nodes := make(map[uint64]*grad.Value[T])
+// trace finds the values reaching root and the edges between them. If
+// maxDepth is above zero then values further than that many steps from
+// the root are left out.
+func trace[T constraints.Float](root *grad.Value[T], maxDepth int) (map[uint64]*grad.Value[T], []edge) {
+	nodes := map[uint64]*grad.Value[T]{root.ID(): root}
+	depth := map[uint64]int{root.ID(): 0}
 	var edges []edge
 
-	var build func (v *grad.Value[T])
-	build = func (v *grad.Value[T]) {
-		if  _, ok := nodes[v.ID()]; ok {
-			return
+	queue := []*grad.Value[T]{root}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		if maxDepth > 0 && depth[v.ID()] >= maxDepth {
+			continue
 		}
 
-		nodes[v.ID()] = v
 		for _, child := range v.Prev() {
 			edges = append(edges, edge{
 				From: child.ID(),
-				To: v.ID(),
+				To:   v.ID(),
 			})
-			build(child)
+
+			if _, ok := nodes[child.ID()]; !ok {
+				nodes[child.ID()] = child
+				depth[child.ID()] = depth[v.ID()] + 1
+				queue = append(queue, child)
+			}
 		}
 	}
-	build(root)
 
 	return nodes, edges
 }
 
-func Render[T constraints.Float](path string, v *grad.Value[T]) error {
-	g := graph.New(uint64Hash, graph.Directed())
+// reaching keeps only the nodes and edges on a path from the value with
+// ID from to the root
+func reaching[T constraints.Float](nodes map[uint64]*grad.Value[T], edges []edge, from uint64) (map[uint64]*grad.Value[T], []edge, error) {
+	if _, ok := nodes[from]; !ok {
+		return nil, nil, fmt.Errorf("value %d is not in the graph", from)
+	}
+
+	parents := make(map[uint64][]uint64)
+	for _, e := range edges {
+		parents[e.From] = append(parents[e.From], e.To)
+	}
+
+	kept := map[uint64]*grad.Value[T]{from: nodes[from]}
+	queue := []uint64{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		for _, p := range parents[id] {
+			if _, ok := kept[p]; !ok {
+				kept[p] = nodes[p]
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	var keptEdges []edge
+	for _, e := range edges {
+		_, fromOk := kept[e.From]
+		_, toOk := kept[e.To]
+		if fromOk && toOk {
+			keptEdges = append(keptEdges, e)
+		}
+	}
+
+	return kept, keptEdges, nil
+}
+
+// paramCluster derives the clusters of a parameter from its name, e.g.
+// "layers.1.neurons.2.w0" is in neuron "layers.1.neurons.2" which is
+// in layer "layers.1"
+func paramCluster(name string, c Clustering) []string {
+	i := strings.LastIndex(name, ".")
+	if c == ClusterNone || i < 0 {
+		return nil
+	}
+
+	neuron := name[:i]
+	layer := neuron
+	if j := strings.LastIndex(neuron, ".neurons."); j >= 0 {
+		layer = neuron[:j]
+	}
+
+	if c == ClusterLayer || layer == neuron {
+		return []string{layer}
+	}
+
+	return []string{layer, neuron}
+}
+
+// commonCluster returns the longest cluster path shared by all of cs
+func commonCluster(cs [][]string) []string {
+	if len(cs) < 1 {
+		return nil
+	}
+
+	common := cs[0]
+	for _, c := range cs[1:] {
+		n := 0
+		for n < len(common) && n < len(c) && common[n] == c[n] {
+			n++
+		}
+		common = common[:n]
+	}
 
-	nodes, edges := trace(v)
+	if len(common) < 1 {
+		return nil
+	}
+
+	return common
+}
 
-	for _, n := range nodes {
-		g.AddVertex(n.ID(), 
-			graph.VertexAttribute("label", fmt.Sprintf("{ %s | data %.4f | grad %.4f }", n.Label(), n.Data(), n.Grad())),
-			graph.VertexAttribute("shape", "record"),
-		)
+// build converts the graph reaching root into vertices in the order they
+// were created, which is also a topological order
+func build[T constraints.Float](root *grad.Value[T], o *renderOptions) (*graphData, error) {
+	nodes, edges := trace(root, o.maxDepth)
 
-		if n.Op() != grad.OpNil {
-			g.AddVertex(n.ID() + opOffset, graph.VertexAttribute("label", string(n.Op())))
-			g.AddEdge(n.ID() + opOffset, n.ID())
+	if o.reaching != nil {
+		var err error
+		if nodes, edges, err = reaching(nodes, edges, *o.reaching); err != nil {
+			return nil, err
 		}
 	}
 
+	// Collapsing needs clusters at least as fine as the summaries
+	clustering := max(o.clustering, o.collapse)
+
+	hasInputs := make(map[uint64]bool)
 	for _, e := range edges {
-		g.AddEdge(e.From, e.To + opOffset)
+		hasInputs[e.To] = true
+	}
+
+	ids := make([]uint64, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	g := graphData{}
+	clusters := make(map[uint64][]string)
+
+	for _, id := range ids {
+		n := nodes[id]
+		v := &vertex{
+			id:    valueID(id),
+			kind:  kindValue,
+			label: n.Label(),
+			op:    n.Op(),
+			data:  float64(n.Data()),
+			grad:  float64(n.Grad()),
+		}
+
+		if name, ok := o.names[id]; ok {
+			v.name = name
+			v.kind = kindParam
+			v.cluster = paramCluster(name, clustering)
+		} else if n.Op() == grad.OpNil {
+			v.kind = kindInput
+		} else if clustering != ClusterNone {
+			// Belong with a parameter input if there is one, otherwise
+			// with whatever the inputs have in common
+			var cs [][]string
+			for _, child := range n.Prev() {
+				c := clusters[child.ID()]
+				if _, ok := o.names[child.ID()]; ok && c != nil {
+					cs = [][]string{c}
+					break
+				}
+				if c != nil {
+					cs = append(cs, c)
+				}
+			}
+			v.cluster = commonCluster(cs)
+		}
+		clusters[id] = v.cluster
+
+		g.vertices = append(g.vertices, v)
+
+		if hasInputs[id] {
+			g.vertices = append(g.vertices, &vertex{
+				id:      opID(id),
+				kind:    kindOp,
+				label:   string(n.Op()),
+				op:      n.Op(),
+				cluster: v.cluster,
+			})
+			g.edges = append(g.edges, vertexEdge{From: opID(id), To: valueID(id)})
+		}
+	}
+
+	for _, e := range edges {
+		g.edges = append(g.edges, vertexEdge{From: valueID(e.From), To: opID(e.To)})
+	}
+
+	if o.collapse != ClusterNone {
+		g.collapse(o.collapse, o.clustering)
+	}
+
+	return &g, nil
+}
+
+var opColors = map[grad.Op]string{
+	grad.OpAdd:    "#aec7e8",
+	grad.OpMul:    "#ffbb78",
+	grad.OpTanh:   "#98df8a",
+	grad.OpRelu:   "#c5b0d5",
+	grad.OpExp:    "#f7b6d2",
+	grad.OpPow:    "#dbdb8d",
+	grad.OpDiv:    "#9edae5",
+	grad.OpLog:    "#c49c94",
+	grad.OpSumSq:  "#ff9896",
+	grad.OpSumAbs: "#ff9896",
+}
+
+const (
+	defaultOpColor = "#d9d9d9"
+	paramColor     = "#1f5fbf"
+	inputColor     = "#2e8b57"
+)
+
+func opColor(op grad.Op) string {
+	if c, ok := opColors[op]; ok {
+		return c
+	}
+
+	return defaultOpColor
+}
+
+// gradColor goes from white at zero to red at the largest gradient
+func gradColor(g, maxGrad float64) string {
+	t := 0.0
+	if maxGrad > 0 && !math.IsNaN(g) {
+		t = min(math.Abs(g)/maxGrad, 1)
+	}
+
+	mix := func(to float64) int {
+		return int(math.Round(255 + t*(to-255)))
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", mix(220), mix(40), mix(40))
+}
+
+// recordEscaper escapes the characters which have a meaning in record
+// labels as well as those in quoted strings
+var recordEscaper = strings.NewReplacer(
+	`\`, `\\`, `"`, `\"`, `{`, `\{`, `}`, `\}`,
+	`|`, `\|`, `<`, `\<`, `>`, `\>`,
+)
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// style is how a vertex is drawn, colours are hex strings and empty for
+// the default
+type style struct {
+	fill     string
+	stroke   string
+	penwidth float64
+}
+
+func (v *vertex) style(o *renderOptions, maxGrad float64) style {
+	var st style
+
+	if v.kind == kindOp {
+		if o.opColors {
+			st.fill = opColor(v.op)
+		}
+
+		return st
+	}
+
+	if v.kind == kindSummary {
+		st.fill = summaryColor
+	}
+
+	if o.gradColors && (v.kind != kindSummary || v.summary.hasData) {
+		st.fill = gradColor(v.grad, maxGrad)
+	}
+
+	if o.highlightParams {
+		switch v.kind {
+		case kindParam:
+			st.stroke = paramColor
+			st.penwidth = 2
+		case kindInput:
+			st.stroke = inputColor
+			st.penwidth = 2
+		}
+	}
+
+	return st
+}
+
+// attrs are the DOT attributes of v, escaped for quoted strings
+func (v *vertex) attrs(o *renderOptions, maxGrad float64) map[string]string {
+	as := make(map[string]string)
+
+	if v.kind == kindOp {
+		as["label"] = quoteEscaper.Replace(v.label)
+	} else {
+		fields := v.fields()
+		for i, f := range fields {
+			fields[i] = recordEscaper.Replace(f)
+		}
+		as["label"] = fmt.Sprintf("{ %s }", strings.Join(fields, " | "))
+		as["shape"] = "record"
+	}
+
+	st := v.style(o, maxGrad)
+	if st.fill != "" {
+		as["style"] = "filled"
+		as["fillcolor"] = st.fill
+	}
+	if st.stroke != "" {
+		as["color"] = st.stroke
+	}
+	if st.penwidth > 0 {
+		as["penwidth"] = fmt.Sprint(st.penwidth)
+	}
+
+	return as
+}
+
+// maxGrad is the largest finite gradient magnitude of the values in g
+func (g *graphData) maxGrad() float64 {
+	m := 0.0
+	for _, v := range g.vertices {
+		if v.kind != kindOp && !math.IsNaN(v.grad) && !math.IsInf(v.grad, 0) {
+			m = max(m, math.Abs(v.grad))
+		}
+	}
+
+	return m
+}
+
+// cluster is a subgraph of vertices and nested clusters
+type cluster struct {
+	name     string
+	vertices []*vertex
+	children []*cluster
+	byName   map[string]*cluster
+}
+
+func (c *cluster) child(name string) *cluster {
+	if ch, ok := c.byName[name]; ok {
+		return ch
+	}
+
+	ch := &cluster{name: name, byName: make(map[string]*cluster)}
+	c.byName[name] = ch
+	c.children = append(c.children, ch)
+
+	return ch
+}
+
+// writeDOT draws g with the graph library. It has no notion of
+// subgraphs, so the clusters are added after the vertices and edges by
+// listing the IDs of their members.
+func writeDOT(w io.Writer, g *graphData, o *renderOptions) error {
+	dg := graph.New(graph.StringHash, graph.Directed())
+
+	maxGrad := g.maxGrad()
+
+	root := &cluster{byName: make(map[string]*cluster)}
+	for _, v := range g.vertices {
+		if err := dg.AddVertex(v.id, graph.VertexAttributes(v.attrs(o, maxGrad))); err != nil {
+			return fmt.Errorf("vertex %s: %w", v.id, err)
+		}
+
+		c := root
+		for _, name := range v.cluster {
+			c = c.child(name)
+		}
+		c.vertices = append(c.vertices, v)
+	}
+
+	for _, e := range g.edges {
+		// The same value can be used twice by one op, e.g. x*x
+		if err := dg.AddEdge(e.From, e.To); err != nil && !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+			return fmt.Errorf("edge %s -> %s: %w", e.From, e.To, err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := draw.DOT(dg, &b, draw.GraphAttribute("rankdir", quoteEscaper.Replace(string(o.direction)))); err != nil {
+		return err
+	}
+
+	var writeCluster func(c *cluster, indent string)
+	writeCluster = func(c *cluster, indent string) {
+		name := quoteEscaper.Replace(c.name)
+		fmt.Fprintf(&b, "%ssubgraph \"cluster_%s\" {\n", indent, name)
+		fmt.Fprintf(&b, "%s\tlabel=\"%s\";\n", indent, name)
+		for _, ch := range c.children {
+			writeCluster(ch, indent+"\t")
+		}
+		for _, v := range c.vertices {
+			fmt.Fprintf(&b, "%s\t\"%s\";\n", indent, v.id)
+		}
+		fmt.Fprintf(&b, "%s}\n", indent)
+	}
+
+	// Reopen the graph to add the clusters
+	b.Truncate(bytes.LastIndexByte(b.Bytes(), '}'))
+	for _, c := range root.children {
+		writeCluster(c, "\t")
+	}
+	b.WriteString("}\n")
+
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// WriteDOT writes the graph reaching v in the Graphviz DOT language
+func WriteDOT[T constraints.Float](w io.Writer, v *grad.Value[T], opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+
+	g, err := build(v, o)
+	if err != nil {
+		return err
+	}
+
+	return writeDOT(w, g, o)
+}
+
+// Render writes the graph reaching v to path. A .gv or .dot file is
+// written in the DOT language which can be drawn by Graphviz, e.g. with
+// dot -Tsvg -O path. A .html file is an interactive page written by
+// WriteHTML. Other extensions, such as .svg or .png, are drawn directly
+// with WriteImage.
+func Render[T constraints.Float](path string, v *grad.Value[T], opts ...RenderOption) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+
+	// Check the format first so that no empty file is left behind
+	var write func(w io.Writer) error
+	switch {
+	case slices.Contains([]string{"", "gv", "dot"}, format):
+		write = func(w io.Writer) error { return WriteDOT(w, v, opts...) }
+	case format == "html" || format == "htm":
+		write = func(w io.Writer) error { return WriteHTML(w, v, opts...) }
+	case slices.Contains(imageFormats, format):
+		write = func(w io.Writer) error { return WriteImage(w, format, v, opts...) }
+	default:
+		return fmt.Errorf("Render %s: unknown format %q", path, format)
 	}
 
 	file, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("Create %s: %w", path, err)
 	}
-	return draw.DOT(g, file, /* draw.GraphAttribute("rankdir", "LR") */)
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		return fmt.Errorf("Write %s: %w", path, err)
+	}
+
+	return file.Close()
 }
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
 
 	"image/color"
 
@@ -9,11 +15,21 @@ import (
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
 
+	"github.com/richiejp/micrograd/internal/cli"
+	"github.com/richiejp/micrograd/internal/dashboard"
 	"github.com/richiejp/micrograd/internal/data"
 	"github.com/richiejp/micrograd/internal/grad"
+	"github.com/richiejp/micrograd/internal/train"
 	"github.com/richiejp/micrograd/internal/viz"
 )
 
+// outDir is where the demos write their plots and graphs
+var outDir = "out"
+
+func outPath(name string) string {
+	return filepath.Join(outDir, name)
+}
+
 func simpleGraph() {
 	gc := &grad.Context[float64]{}
 
@@ -33,8 +49,10 @@ func simpleGraph() {
 	o := e.Add(gc.Val(-1), gc.WithLabel("e-1")).Div(e.Add(gc.Val(1), gc.WithLabel("e+1")), gc.WithLabel("o"))
 	gc.Backward(o)
 
-	if err := viz.Render("./out/graph.gv", o); err != nil {
-		panic(err)
+	for _, path := range []string{outPath("graph.gv"), outPath("graph.svg")} {
+		if err := viz.Render(path, o, viz.WithDirection(viz.LeftToRight), viz.WithOpColors(), viz.WithGradColors()); err != nil {
+			panic(err)
+		}
 	}
 
 }
@@ -112,15 +130,30 @@ func trainNet() {
 		}
 	}
 
-	if err := viz.Render("./out/mlp.gv", ypred[0]); err != nil {
-		panic(err)
+	for _, path := range []string{outPath("mlp.gv"), outPath("mlp.svg"), outPath("mlp.html")} {
+		if err := viz.Render(path, ypred[0],
+			viz.WithDirection(viz.LeftToRight),
+			viz.WithModule(n),
+			viz.WithClusters(viz.ClusterNeuron),
+			viz.WithParamHighlight(),
+			viz.WithGradColors(),
+		); err != nil {
+			panic(err)
+		}
 	}
 }
 
-// Similar to demo.ipynb in the repo
-func demo() {
+// Similar to demo.ipynb in the repo, if dash is not nil then training
+// can be followed on it
+func demo(dash *dashboard.Dashboard) {
 	X, y := data.MakeMoons(100, 0.1, true)
 
+	// Hold out a test set to measure generalization
+	trainIdx, testIdx, err := data.TrainTestSplit(y, 0.2, true, 1)
+	if err != nil {
+		panic(err)
+	}
+
 	for i, yi := range y {
 		y[i] = 2*yi - 1
 	}
@@ -149,19 +182,21 @@ func demo() {
 	p.Legend.Add("Class 0", s0)
 	p.Legend.Add("Class 1", s1)
 
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, "./out/moons.png"); err != nil {
+	if err := p.Save(6*vg.Inch, 6*vg.Inch, outPath("moons.png")); err != nil {
 		panic(err)
 	}
 
-	gc := &grad.Context[float64]{}
+	testX, testY := data.Take(X, y, testIdx)
+	X, y = data.Take(X, y, trainIdx)
 
-	model := gc.MLP(2, 16, 16, 1)
+	gc := &grad.Context[float64]{}
 
 	// The activation functions differ between the video and the repository
-	for l := range model.Depth() {
-		model.SetActFn(l, grad.ReluActFn)
+	model, err := gc.MLPWith([]uint{2, 16, 16, 1},
+		gc.WithActFns(grad.ReluActFn, grad.ReluActFn, grad.LinearActFn))
+	if err != nil {
+		panic(err)
 	}
-	model.SetActFn(model.Depth() - 1, grad.LinearActFn)
 
 	inputs := make([][]*grad.Value[float64], len(X))
 	for i, xrow := range X {
@@ -173,15 +208,42 @@ func demo() {
 		expected[i] = gc.Val(float64(yi))
 	}
 
-	zero := gc.Val(0)
 	one := gc.Val(1)
 	losses_len := gc.Val(float64(len(inputs)))
-	alpha := gc.Val(1e-4)
+	reg := grad.L2(1e-4)
+
+	if err := viz.Render(outPath("demo.gv"), model.Forward(inputs[0])[0],
+		viz.WithDirection(viz.LeftToRight),
+		viz.WithModule(model),
+		viz.WithClusters(viz.ClusterLayer),
+		viz.WithOpColors(),
+		viz.WithParamHighlight(),
+	); err != nil {
+		panic(err)
+	}
 
-	if err := viz.Render("./out/demo.gv", model.Forward(inputs[0])[0]); err != nil {
+	// The full graph is too large to read so also draw a summary
+	if err := viz.Render(outPath("demo.svg"), model.Forward(inputs[0])[0],
+		viz.WithDirection(viz.LeftToRight),
+		viz.WithModule(model),
+		viz.WithCollapse(viz.ClusterNeuron),
+		viz.WithClusters(viz.ClusterLayer),
+	); err != nil {
 		panic(err)
 	}
 
+	testInputs := make([][]*grad.Value[float64], len(testX))
+	for i, xrow := range testX {
+		testInputs[i] = gc.Vals(xrow...)
+	}
+
+	labels := make([]int, len(y))
+	for i, yi := range y {
+		labels[i] = (yi + 1) / 2
+	}
+
+	history := &train.History{}
+
 	for k := range 100 {
 		scores := make([]*grad.Value[float64], len(inputs))
 		for i, input := range inputs {
@@ -196,11 +258,7 @@ func demo() {
 		data_loss := gc.Sum(losses).Div(losses_len)
 
 		// L2 regularization
-		square_params := zero
-		for p := range model.Parameters() {
-			square_params = square_params.Add(p.Mul(p))
-		}
-		reg_loss := square_params.Mul(alpha)
+		reg_loss := reg.Term(gc, model.Parameters())
 		total_loss := data_loss.Add(reg_loss)
 
 		correct := 0
@@ -210,20 +268,194 @@ func demo() {
 			}
 		}
 		accuracy := float64(correct) / float64(len(y))
+
+		val := train.Metrics{}
+		for i, input := range testInputs {
+			score := model.Forward(input)[0].Data()
+			val.Loss += max(0, 1-float64(testY[i])*score) / float64(len(testY))
+			if (testY[i] > 0) == (score > 0) {
+				val.Accuracy += 1 / float64(len(testY))
+			}
+		}
 		
 		gc.Backward(total_loss)
+		grad_norm := grad.GradNorm(model.Parameters())
 
 		learning_rate := 0.5 // 1.0 - 0.9*float64(k)/100.0
 		for p := range model.Parameters() {
 			p.Descend(-learning_rate)
 		}
 
+		fmt.Printf("Step %v loss %v, accuracy %.1f%%, grad norm %.3f\n", k, total_loss.Data(), 100*accuracy, grad_norm)
+
+		step := train.Step{
+			Step:         k,
+			Train:        train.Metrics{Loss: data_loss.Data(), Accuracy: accuracy},
+			Val:          &val,
+			LearningRate: learning_rate,
+			GradNorm:     grad_norm,
+		}
+		history.Record(step)
+
+		if dash == nil {
+			continue
+		}
+
+		dash.Record(step)
+
+		// Drawing the boundary is slow compared to a step
+		if k%10 == 9 {
+			var png bytes.Buffer
+			if err := viz.WriteBoundary(&png, "png", gc, model, X, labels, viz.BoundaryOptions{}); err != nil {
+				panic(err)
+			}
+			dash.SetBoundary(png.Bytes())
+		}
+	}
+
+	correct := 0
+	for i, xrow := range testX {
+		if (testY[i] > 0) == (model.Forward(gc.Vals(xrow...))[0].Data() > 0) {
+			correct += 1
+		}
+	}
+	fmt.Printf("Test accuracy %.1f%%\n", 100*float64(correct)/float64(len(testY)))
+
+	if err := viz.PlotHistory(outPath("history.png"), history); err != nil {
+		panic(err)
+	}
+
+	if err := viz.DecisionBoundary(outPath("boundary.png"), gc, model, X, labels, viz.BoundaryOptions{}); err != nil {
+		panic(err)
+	}
+}
+
+// 5-fold cross-validation of the demo model
+func crossValidate() {
+	X, y := data.MakeMoonsSeeded(100, 0.1, true, 1)
+
+	folds, err := data.KFold(y, 5, true, 1)
+	if err != nil {
+		panic(err)
+	}
+
+	res, err := train.CrossValidate(X, y, folds, train.Options{L2: 1e-4, ClipNorm: 5, Seed: 1})
+	if err != nil {
+		panic(err)
+	}
+
+	for i, m := range res.Folds {
+		fmt.Printf("Fold %v loss %.4f, accuracy %.1f%%\n", i, m.Loss, 100*m.Accuracy)
+	}
+	fmt.Printf("Cross-validation accuracy %.1f%% ± %.1f%%\n", 100*res.Mean.Accuracy, 100*res.Std.Accuracy)
+}
+
+// Three class version of demo using softmax cross-entropy
+func multiClassDemo() {
+	centers := [][]float64{{-2, 0}, {2, 0}, {0, 2.5}}
+	X, y, err := data.MakeBlobs(150, centers, 0.8, 1)
+	if err != nil {
+		panic(err)
+	}
+
+	gc := &grad.Context[float64]{}
+
+	model, err := gc.MLPWith([]uint{2, 16, uint(len(centers))},
+		gc.WithActFns(grad.ReluActFn, grad.LinearActFn))
+	if err != nil {
+		panic(err)
+	}
+
+	inputs := make([][]*grad.Value[float64], len(X))
+	for i, xrow := range X {
+		inputs[i] = gc.Vals(xrow...)
+	}
+
+	losses_len := gc.Val(float64(len(inputs)))
+
+	for k := range 50 {
+		losses := make([]*grad.Value[float64], len(inputs))
+		correct := 0
+		for i, input := range inputs {
+			logits := model.Forward(input)
+			losses[i] = gc.CrossEntropy(logits, y[i])
+
+			if grad.Argmax(logits) == y[i] {
+				correct += 1
+			}
+		}
+		total_loss := gc.Sum(losses).Div(losses_len)
+		accuracy := float64(correct) / float64(len(y))
+
+		gc.Backward(total_loss)
+
+		for p := range model.Parameters() {
+			p.Descend(-0.1)
+		}
+
 		fmt.Printf("Step %v loss %v, accuracy %.1f%%\n", k, total_loss.Data(), 100*accuracy)
 	}
 }
 
-func main() {
+// runDemos runs the examples which main used to run unconditionally
+func runDemos(env *cli.Env, args []string) error {
+	fs := env.Flags("demo", "")
+	fs.StringVar(&outDir, "out", outDir, "directory for the plots and graphs")
+	addr := fs.String("dashboard", "", "serve a live training dashboard on this address, e.g. localhost:8080")
+
+	if err := env.Parse(fs, args); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	var dash *dashboard.Dashboard
+	if *addr != "" {
+		dash = dashboard.New("makeMoons demo")
+		url, err := dash.Listen(*addr)
+		if err != nil {
+			return err
+		}
+		defer dash.Close()
+
+		fmt.Printf("Dashboard at %s\n", url)
+	}
+
 	simpleGraph()
 	trainNet()
-	demo()
+	demo(dash)
+	multiClassDemo()
+	crossValidate()
+
+	if dash == nil {
+		return nil
+	}
+
+	// Keep showing the results until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Println("Press Ctrl+C to stop the dashboard")
+	<-ctx.Done()
+
+	return nil
+}
+
+func main() {
+	err := cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, cli.Command{
+		Name:    "demo",
+		Summary: "run the examples from the video, writing plots and graphs to -out",
+		Run:     runDemos,
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, cli.ErrUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cli.Name, err)
+		os.Exit(1)
+	}
 }
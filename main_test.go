@@ -2,6 +2,8 @@ package main_test
 
 import (
 	"fmt"
+	"math"
+	"slices"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -143,7 +145,14 @@ var _ = Describe("Main", func() {
 		x := gc.Vals(1, 2, 3)
 		a := n.Forward(x)
 
-		Expect(a.Data()).To(BeNumerically("~", 0.998, 0.01))
+		// The weights are random so compare with tanh(w·x + b), the
+		// parameters are the bias followed by the weights
+		params := slices.Collect(n.Parameters())
+		want := params[0].Data()
+		for i, w := range params[1:] {
+			want += w.Data() * x[i].Data()
+		}
+		Expect(a.Data()).To(BeNumerically("~", math.Tanh(want), 1e-12))
 	})
 
 	It("Can calculate the loss", func() {
@@ -0,0 +1,66 @@
+package main_test
+
+import (
+	"iter"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+// A custom module with no parameters
+type double struct{}
+
+func (double) Forward(inputs []*grad.Value[float64]) []*grad.Value[float64] {
+	outs := make([]*grad.Value[float64], len(inputs))
+	for i, x := range inputs {
+		outs[i] = x.Add(x)
+	}
+	return outs
+}
+
+func (double) Parameters() iter.Seq[*grad.Value[float64]] {
+	return func(yield func(*grad.Value[float64]) bool) {}
+}
+
+func (double) NamedParameters() iter.Seq2[string, *grad.Value[float64]] {
+	return func(yield func(string, *grad.Value[float64]) bool) {}
+}
+
+var _ = Describe("Module", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+	})
+
+	It("Can compose layers and custom modules", func() {
+		l1 := gc.Lay(2, 3)
+		l2 := gc.Lay(3, 1)
+		s := gc.Seq(l1, double{}, l2)
+
+		x := gc.Vals(0.5, -0.5)
+		out := s.Forward(x)
+		Expect(out).To(HaveLen(1))
+		Expect(out[0].Data()).To(Equal(l2.Forward(double{}.Forward(l1.Forward(x)))[0].Data()))
+
+		count := 0
+		for range s.Parameters() {
+			count++
+		}
+		Expect(count).To(Equal(3*3 + 4))
+
+		sd := s.StateDict()
+		Expect(sd).To(HaveLen(count))
+		Expect(sd).To(HaveKey("0.neurons.2.w1"))
+		Expect(sd).To(HaveKey("2.neurons.0.w2"))
+	})
+
+	It("Can treat an MLP generically", func() {
+		var m grad.Module[float64] = gc.MLP(2, 2, 1)
+		other := gc.MLP(2, 2, 1)
+
+		Expect(grad.LoadStateDictInto(m, grad.StateDictOf[float64](other))).To(Succeed())
+		Expect(grad.StateDictOf(m).Diff(other.StateDict(), 0)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,137 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Normalization", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		gc.Seed(3)
+	})
+
+	batch := func() [][]*grad.Value[float64] {
+		return [][]*grad.Value[float64]{
+			gc.Vals(1, -2, 0.5),
+			gc.Vals(3, 0.5, -1),
+			gc.Vals(-0.5, 1, 2),
+			gc.Vals(0.2, 0.3, 0.1),
+		}
+	}
+
+	It("Normalises a sample with LayerNorm", func() {
+		ln := gc.LayerNorm(4)
+		out := ln.Forward(gc.Vals(1, 2, 3, 10))
+
+		mean, sq := 0.0, 0.0
+		for _, o := range out {
+			mean += o.Data() / 4
+			sq += o.Data() * o.Data() / 4
+		}
+		Expect(mean).To(BeNumerically("~", 0, 1e-9))
+		Expect(sq).To(BeNumerically("~", 1, 1e-4))
+	})
+
+	It("Has correct LayerNorm gradients", func() {
+		s := gc.Seq(gc.Lay(3, 4), gc.LayerNorm(4), gc.Lay(4, 1))
+		xs := batch()
+		params := append(xs[0], xs[1]...)
+		for p := range s.Parameters() {
+			params = append(params, p)
+		}
+
+		worst := must(gc.CheckGrad(params, func() *grad.Value[float64] {
+			loss := gc.Val(0)
+			for _, x := range xs {
+				loss = loss.Add(s.Forward(x)[0].Pow(2))
+			}
+			return loss
+		}, 1e-6))
+		Expect(worst).To(BeNumerically("<", 1e-5))
+	})
+
+	It("Has correct BatchNorm gradients", func() {
+		bn := gc.BatchNorm(4)
+		s := gc.Seq(gc.Lay(3, 4), bn, gc.Lay(4, 1))
+		xs := batch()
+		params := append(xs[0], xs[2]...)
+		for p := range s.Parameters() {
+			params = append(params, p)
+		}
+
+		stats := s.StateDict()
+		worst := must(gc.CheckGrad(params, func() *grad.Value[float64] {
+			loss := gc.Val(0)
+			for _, out := range s.ForwardBatch(xs) {
+				loss = loss.Add(out[0].Pow(2))
+			}
+			return loss
+		}, 1e-6))
+		Expect(worst).To(BeNumerically("<", 1e-5))
+
+		Expect(stats.Diff(s.StateDict(), 0)).To(BeEmpty())
+		Expect(bn.RunningMean()).To(Equal([]float64{0, 0, 0, 0}))
+	})
+
+	It("Normalises a batch and tracks running statistics", func() {
+		bn := gc.BatchNorm(3)
+		outs := bn.ForwardBatch(batch())
+
+		for j := range 3 {
+			mean := 0.0
+			for _, o := range outs {
+				mean += o[j].Data() / 4
+			}
+			Expect(mean).To(BeNumerically("~", 0, 1e-9))
+		}
+		Expect(bn.RunningMean()[0]).To(BeNumerically("~", 0.1*(1+3-0.5+0.2)/4))
+
+		bn.SetTraining(false)
+		mean := bn.RunningMean()[0]
+		out := bn.ForwardBatch(batch())
+		Expect(bn.RunningMean()[0]).To(Equal(mean))
+		Expect(out[0][0].Data()).To(Equal(bn.Forward(gc.Vals(1, -2, 0.5))[0].Data()))
+	})
+
+	It("Saves the running statistics in the state dict", func() {
+		s := gc.Seq(gc.Lay(3, 3), gc.BatchNorm(3))
+		s.ForwardBatch(batch())
+		s.SetTraining(false)
+
+		sd := s.StateDict()
+		Expect(sd).To(HaveKey("1.running_mean.2"))
+		Expect(sd).To(HaveKey("1.running_var.0"))
+		Expect(sd["1.running_mean.0"]).ToNot(BeZero())
+
+		other := gc.Seq(gc.Lay(3, 3), gc.BatchNorm(3))
+		other.SetTraining(false)
+		Expect(other.LoadStateDict(sd)).To(Succeed())
+		Expect(other.StateDict().Diff(sd, 0)).To(BeEmpty())
+
+		x := gc.Vals(0.5, -1, 2)
+		want := s.Forward(x)
+		for i, o := range other.Forward(x) {
+			Expect(o.Data()).To(Equal(want[i].Data()))
+		}
+	})
+
+	It("Rejects batches of the wrong width", func() {
+		bn := gc.BatchNorm(3)
+		xs := batch()
+		xs[2] = gc.Vals(1, 2)
+
+		_, err := bn.ForwardBatchE(xs)
+		Expect(err).To(MatchError(grad.ErrInputSize))
+		Expect(err).To(MatchError(ContainSubstring("sample 2")))
+		Expect(bn.RunningMean()).To(Equal([]float64{0, 0, 0}))
+
+		_, err = grad.ForwardBatchE[float64](gc.Seq(gc.Lay(3, 2), gc.BatchNorm(2)), xs)
+		Expect(err).To(MatchError(grad.ErrInputSize))
+
+		Expect(func() { bn.ForwardBatch(xs) }).To(Panic())
+	})
+})
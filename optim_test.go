@@ -0,0 +1,82 @@
+package main_test
+
+import (
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/grad"
+	"github.com/richiejp/micrograd/internal/train"
+)
+
+var _ = Describe("Optimizers", func() {
+	var gc *grad.Context[float64]
+	var p *grad.Value[float64]
+	var loss *grad.Value[float64]
+
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		p = gc.Val(1)
+		// The gradient of 3p is always 3
+		loss = p.Mul(gc.Val(3))
+	})
+
+	step := func(o grad.Optimizer[float64], lr float64) float64 {
+		Expect(gc.Backward(loss)).To(Succeed())
+		o.Step(slices.Values([]*grad.Value[float64]{p}), lr)
+		return p.Data()
+	}
+
+	It("Descends the gradient with SGD", func() {
+		o, err := grad.NewOptimizer[float64](grad.SGDOptim)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(step(o, 0.1)).To(BeNumerically("~", 0.7))
+		Expect(step(o, 0.1)).To(BeNumerically("~", 0.4))
+	})
+
+	It("Accumulates velocity with momentum", func() {
+		o := grad.NewMomentum[float64](0.9)
+
+		Expect(step(o, 0.1)).To(BeNumerically("~", 0.7))
+		// v = 0.9*3 + 3
+		Expect(step(o, 0.1)).To(BeNumerically("~", 0.13))
+	})
+
+	It("Takes steps of about the learning rate with Adam", func() {
+		o, err := grad.NewOptimizer[float64](grad.AdamOptim)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(step(o, 0.1)).To(BeNumerically("~", 0.9, 1e-6))
+		Expect(step(o, 0.1)).To(BeNumerically("~", 0.8, 1e-6))
+	})
+
+	It("Rejects unknown optimizers", func() {
+		_, err := grad.NewOptimizer[float64]("lbfgs")
+		Expect(err).To(MatchError(grad.ErrUnknownOptim))
+
+		X, y := data.MakeMoonsSeeded(20, 0.1, true, 1)
+		_, err = train.Fit(X, y, train.Options{Steps: 1, Optimizer: "lbfgs"})
+		Expect(err).To(MatchError(grad.ErrUnknownOptim))
+	})
+
+	It("Trains a classifier", func() {
+		X, y := data.MakeMoonsSeeded(100, 0.1, true, 1)
+
+		for _, o := range []grad.Optim{grad.SGDOptim, grad.MomentumOptim, grad.AdamOptim} {
+			opts := train.Options{Hidden: []uint{8}, Steps: 50, Optimizer: o, Seed: 1, ClipNorm: 5}
+			if o == grad.MomentumOptim {
+				opts.LearningRate = 0.05
+			}
+
+			c, err := train.Fit(X, y, opts)
+			Expect(err).ToNot(HaveOccurred())
+
+			m, err := c.Evaluate(X, y)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Accuracy).To(BeNumerically(">", 0.8), string(o))
+		}
+	})
+})
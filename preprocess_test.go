@@ -0,0 +1,128 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/train"
+)
+
+var _ = Describe("Preprocessing", func() {
+	X := [][]float64{
+		{1, 10, 0},
+		{2, 20, 1},
+		{3, 30, 2},
+		{4, 1000, 1},
+	}
+
+	It("Standardises features", func() {
+		s := &data.StandardScaler{}
+		out, err := data.FitTransform(s, X)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(s.Mean[0]).To(BeNumerically("~", 2.5))
+		sum := 0.0
+		for _, x := range out {
+			sum += x[0]
+		}
+		Expect(sum).To(BeNumerically("~", 0))
+		Expect(X[0][0]).To(Equal(1.0))
+	})
+
+	It("Scales to a range", func() {
+		out, err := data.FitTransform(&data.MinMaxScaler{Low: -1, High: 1}, X)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(out[0][0]).To(BeNumerically("~", -1))
+		Expect(out[3][0]).To(BeNumerically("~", 1))
+		Expect(out[1][2]).To(BeNumerically("~", 0))
+	})
+
+	It("Defaults to the unit range without changing the scaler's range", func() {
+		s := &data.MinMaxScaler{}
+		out, err := data.FitTransform(s, X)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(out[0][0]).To(BeNumerically("~", 0))
+		Expect(out[3][0]).To(BeNumerically("~", 1))
+		Expect(s.Low).To(BeZero())
+		Expect(s.High).To(BeZero())
+	})
+
+	It("Scales robustly", func() {
+		s := &data.RobustScaler{}
+		Expect(s.Fit(X)).To(Succeed())
+
+		Expect(s.Center[1]).To(Equal(25.0))
+		Expect(s.Scale[1]).To(Equal(272.5 - 17.5))
+	})
+
+	It("Encodes categories", func() {
+		out, err := data.FitTransform(&data.OneHotEncoder{Columns: []int{2}}, X)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out[2]).To(Equal([]float64{3, 30, 0, 0, 1}))
+
+		e := &data.OneHotEncoder{Columns: []int{2}}
+		Expect(e.Fit(X)).To(Succeed())
+		_, err = e.Transform([][]float64{{1, 1, 7}})
+		Expect(err).To(MatchError(ContainSubstring("unknown category")))
+	})
+
+	It("Makes polynomial features", func() {
+		out, err := data.FitTransform(&data.PolynomialFeatures{}, [][]float64{{2, 3}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out[0]).To(Equal([]float64{2, 3, 4, 6, 9}))
+	})
+
+	It("Refuses to transform before fitting", func() {
+		_, err := (&data.StandardScaler{}).Transform(X)
+		Expect(err).To(MatchError(data.ErrNotFitted))
+	})
+
+	It("Saves a pipeline as JSON", func() {
+		p := data.Pipeline{&data.PolynomialFeatures{Degree: 2}, &data.StandardScaler{}}
+		want, err := p.FitTransform(X)
+		Expect(err).ToNot(HaveOccurred())
+
+		b, err := json.Marshal(p)
+		Expect(err).ToNot(HaveOccurred())
+
+		var q data.Pipeline
+		Expect(json.Unmarshal(b, &q)).To(Succeed())
+		Expect(q).To(HaveLen(2))
+		Expect(q.Transform(X)).To(Equal(want))
+	})
+
+	It("Saves preprocessing with a model checkpoint", func() {
+		X, y, err := data.MakeBlobs(40, [][]float64{{100, 0}, {110, 0}}, 1, 1)
+		Expect(err).ToNot(HaveOccurred())
+		pre := data.Pipeline{&data.StandardScaler{}}
+		Expect(pre.Fit(X)).To(Succeed())
+
+		c, err := train.New(2, 2, train.Options{Hidden: []uint{4}, Seed: 1})
+		Expect(err).ToNot(HaveOccurred())
+		c.SetPreprocess(pre)
+		Expect(c.Train(X, y, train.Options{Steps: 20})).To(Succeed())
+
+		var buf bytes.Buffer
+		Expect(c.Save(&buf)).To(Succeed())
+		d, err := train.Load(&buf)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, x := range X {
+			Expect(d.Predict(x)).To(Equal(must(c.Predict(x))))
+		}
+		m, err := d.Evaluate(X, y)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Accuracy).To(BeNumerically(">", 0.9))
+	})
+})
+
+func must[T any](v T, err error) T {
+	Expect(err).ToNot(HaveOccurred())
+	return v
+}
@@ -0,0 +1,79 @@
+package main_test
+
+import (
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Regularization", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		gc.Seed(5)
+	})
+
+	It("Matches the hand built L2 term", func() {
+		model := gc.MLP(2, 4, 1)
+
+		square_params := gc.Val(0)
+		for p := range model.Parameters() {
+			square_params = square_params.Add(p.Mul(p))
+		}
+		expected := square_params.Mul(gc.Val(1e-4))
+
+		term := grad.L2(1e-4).Term(gc, model.Parameters())
+		Expect(term.Data()).To(BeNumerically("~", expected.Data()))
+	})
+
+	It("Has correct gradients", func() {
+		ps := gc.Vals(0.5, -2, 3)
+		r := grad.ElasticNet(0.1, 0.3)
+
+		worst := must(gc.CheckGrad(ps, func() *grad.Value[float64] {
+			return r.Term(gc, slices.Values(ps))
+		}, 1e-6))
+		Expect(worst).To(BeNumerically("<", 1e-6))
+	})
+
+	It("Decays parameters like the gradient of the term", func() {
+		ps := gc.Vals(0.5, -2, 3)
+		qs := gc.Vals(0.5, -2, 3)
+		r := grad.ElasticNet(0.1, 0.3)
+
+		gc.Backward(r.Term(gc, slices.Values(ps)))
+		for _, p := range ps {
+			p.Descend(-0.5)
+		}
+		r.Decay(slices.Values(qs), 0.5)
+
+		for i := range ps {
+			Expect(qs[i].Data()).To(BeNumerically("~", ps[i].Data()))
+		}
+	})
+
+	It("Can exclude biases", func() {
+		model := gc.MLP(2, 3, 1)
+		reg := grad.Regularization[float64]{
+			{Match: grad.WeightsOnly, Reg: grad.L2(1.0)},
+		}
+
+		gc.Backward(reg.Term(gc, model.NamedParameters()))
+		for name, p := range model.NamedParameters() {
+			if grad.IsBias(name) {
+				Expect(p.Grad()).To(Equal(0.0))
+			} else {
+				Expect(p.Grad()).To(BeNumerically("~", 2*p.Data()))
+			}
+		}
+
+		before := model.StateDict()
+		reg.Decay(model.NamedParameters(), 0.1)
+		for _, d := range before.Diff(model.StateDict(), 0) {
+			Expect(grad.IsBias(d.Name)).To(BeFalse())
+		}
+	})
+})
@@ -0,0 +1,221 @@
+package main_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+	"github.com/richiejp/micrograd/internal/viz"
+)
+
+var _ = Describe("Render", func() {
+	var gc *grad.Context[float64]
+	var model *grad.MLP[float64]
+	var out *grad.Value[float64]
+
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+		gc.Seed(1)
+		model = gc.MLP(2, 2, 1)
+		out = model.Forward(gc.Vals(1, 2))[0]
+		gc.Backward(out)
+	})
+
+	dot := func(opts ...viz.RenderOption) string {
+		var b strings.Builder
+		Expect(viz.WriteDOT(&b, out, opts...)).To(Succeed())
+		return b.String()
+	}
+
+	It("Writes a DOT file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "mlp.gv")
+		Expect(viz.Render(path, out)).To(Succeed())
+
+		b, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(HavePrefix("strict digraph {"))
+		Expect(string(b)).To(ContainSubstring(`rankdir="TB"`))
+		Expect(string(b)).To(ContainSubstring(`label="tanh"`))
+		Expect(string(b)).ToNot(ContainSubstring("subgraph"))
+	})
+
+	It("Sets the direction", func() {
+		Expect(dot(viz.WithDirection(viz.LeftToRight))).To(ContainSubstring(`rankdir="LR"`))
+	})
+
+	It("Clusters by layer", func() {
+		s := dot(viz.WithModule(model), viz.WithClusters(viz.ClusterLayer))
+
+		Expect(s).To(ContainSubstring(`subgraph "cluster_layers.0"`))
+		Expect(s).To(ContainSubstring(`subgraph "cluster_layers.1"`))
+		Expect(s).ToNot(ContainSubstring(`cluster_layers.0.neurons.0`))
+	})
+
+	It("Clusters by neuron", func() {
+		s := dot(viz.WithModule(model), viz.WithClusters(viz.ClusterNeuron))
+
+		Expect(strings.Count(s, "subgraph")).To(Equal(5))
+		Expect(s).To(ContainSubstring(`subgraph "cluster_layers.0.neurons.1"`))
+
+		// The neuron's output is computed inside its cluster
+		neuron := s[strings.Index(s, `"cluster_layers.1.neurons.0"`):]
+		neuron = neuron[:strings.Index(neuron, "}\n")]
+		Expect(neuron).To(ContainSubstring(fmt.Sprintf(`"v%d";`, out.ID())))
+		Expect(neuron).To(ContainSubstring(fmt.Sprintf(`"op%d";`, out.ID())))
+		Expect(s).To(ContainSubstring(fmt.Sprintf(`"op%d" [ label="tanh"`, out.ID())))
+	})
+
+	It("Highlights parameters and inputs", func() {
+		s := dot(viz.WithModule(model), viz.WithParamHighlight())
+
+		Expect(strings.Count(s, `color="#1f5fbf"`)).To(Equal(9))
+		Expect(strings.Count(s, `color="#2e8b57"`)).To(Equal(2))
+	})
+
+	It("Colours ops and gradients", func() {
+		Expect(dot()).ToNot(ContainSubstring("fillcolor"))
+
+		s := dot(viz.WithOpColors())
+		Expect(s).To(ContainSubstring(`fillcolor="#ffbb78", label="*", style="filled"`))
+
+		// The root has the largest gradient
+		s = dot(viz.WithGradColors())
+		Expect(s).To(ContainSubstring(`fillcolor="#dc2828", label="{ out | data 0.3715 | grad 1.0000 }", shape="record", style="filled"`))
+	})
+
+	It("Gives every node a unique ID in large graphs", func() {
+		// Enough values that their IDs overlap any fixed offset for ops
+		const n = 12000
+		gc = &grad.Context[float64]{}
+		out = gc.Val(0)
+		for i := range n {
+			out = out.Add(gc.Val(float64(i)))
+		}
+
+		ids := make(map[string]bool)
+		edges := 0
+		for _, line := range strings.Split(dot(), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.Contains(line, " -> ") {
+				edges++
+				continue
+			}
+
+			id, _, ok := strings.Cut(line, " [ ")
+			if !ok {
+				continue
+			}
+			if ids[id] {
+				Fail("duplicate ID " + id)
+			}
+			ids[id] = true
+		}
+
+		// 1 + 2n values and n ops, each op has two inputs and an output
+		Expect(ids).To(HaveLen(1 + 3*n))
+		Expect(edges).To(Equal(3 * n))
+	})
+
+	It("Draws images without Graphviz", func() {
+		dir := GinkgoT().TempDir()
+
+		png := filepath.Join(dir, "mlp.png")
+		Expect(viz.Render(png, out, viz.WithModule(model), viz.WithClusters(viz.ClusterNeuron))).To(Succeed())
+		b, err := os.ReadFile(png)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(HavePrefix("\x89PNG"))
+
+		for _, d := range []viz.Direction{viz.TopToBottom, viz.LeftToRight, viz.BottomToTop, viz.RightToLeft} {
+			var svg strings.Builder
+			Expect(viz.WriteImage(&svg, "svg", out, viz.WithDirection(d), viz.WithOpColors())).To(Succeed())
+			Expect(svg.String()).To(ContainSubstring("<svg"))
+			Expect(svg.String()).To(ContainSubstring("tanh"))
+			Expect(svg.String()).To(ContainSubstring("grad 1.0000"))
+		}
+
+		Expect(viz.Render(filepath.Join(dir, "mlp.xyz"), out)).To(MatchError(ContainSubstring(`unknown format "xyz"`)))
+		Expect(filepath.Join(dir, "mlp.xyz")).ToNot(BeAnExistingFile())
+	})
+
+	It("Draws large graphs", func() {
+		gc = &grad.Context[float64]{}
+		model = gc.MLP(2, 16, 16, 1)
+		out = model.Forward(gc.Vals(1, 2))[0]
+
+		var b strings.Builder
+		Expect(viz.WriteImage(&b, "png", out, viz.WithDirection(viz.LeftToRight))).To(Succeed())
+		Expect(b.Len()).To(BeNumerically(">", 0))
+	})
+
+	It("Collapses neurons and layers", func() {
+		s := dot(viz.WithModule(model), viz.WithCollapse(viz.ClusterNeuron), viz.WithClusters(viz.ClusterLayer))
+
+		Expect(s).To(ContainSubstring(`{ layers.0.neurons.1 | 8 values, 3 params | data -0.9859 | grad -0.3215 }`))
+		Expect(s).To(ContainSubstring(`{ layers.1.neurons.0 | 8 values, 3 params | data 0.3715 | grad 1.0000 }`))
+		Expect(strings.Count(s, "subgraph")).To(Equal(2))
+		Expect(s).ToNot(ContainSubstring(`label="tanh"`))
+
+		s = dot(viz.WithModule(model), viz.WithCollapse(viz.ClusterLayer))
+		Expect(s).To(ContainSubstring(`{ layers.0 | 16 values, 6 params }`))
+		Expect(s).To(ContainSubstring(`"s0" -> "s1"`))
+		// Two inputs to layer 0 and layer 0 to layer 1
+		Expect(strings.Count(s, " -> ")).To(Equal(3))
+	})
+
+	It("Limits the depth", func() {
+		s := dot(viz.WithMaxDepth(1))
+
+		Expect(strings.Count(s, "shape=\"record\"")).To(Equal(2))
+		Expect(s).To(ContainSubstring(`label="tanh"`))
+		Expect(s).ToNot(ContainSubstring(`label="+"`))
+	})
+
+	It("Renders the paths from a parameter", func() {
+		var w1 *grad.Value[float64]
+		for name, p := range model.NamedParameters() {
+			if name == "layers.0.neurons.1.w1" {
+				w1 = p
+			}
+		}
+
+		s := dot(viz.WithReaching(w1))
+		// w1 -> * -> + -> tanh -> * -> + -> tanh
+		Expect(strings.Count(s, "shape=\"record\"")).To(Equal(7))
+		Expect(s).To(ContainSubstring("{ w1 | data -0.9873"))
+		Expect(s).ToNot(ContainSubstring("{ w0 |"))
+
+		Expect(viz.WriteDOT(&strings.Builder{}, out, viz.WithReaching(gc.Val(1)))).ToNot(Succeed())
+	})
+
+	It("Writes a self-contained HTML explorer", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "mlp.html")
+		Expect(viz.Render(path, out, viz.WithModule(model), viz.WithClusters(viz.ClusterLayer))).To(Succeed())
+
+		b, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		s := string(b)
+		Expect(s).To(HavePrefix("<!DOCTYPE html>"))
+		Expect(s).To(ContainSubstring("<script>"))
+		Expect(s).To(ContainSubstring(`"op":"tanh"`))
+		Expect(s).To(ContainSubstring(`"name":"layers.0.neurons.1.w1"`))
+		Expect(s).To(ContainSubstring(`"name":"layers.1"`))
+		Expect(s).To(ContainSubstring(`"grad":"1"`))
+		// Nothing is loaded from elsewhere
+		Expect(s).ToNot(MatchRegexp(`(src|href)="?https?:`))
+	})
+
+	It("Escapes labels", func() {
+		out = gc.Val(1, gc.WithLabel(`a|"b"`))
+		Expect(dot()).To(ContainSubstring(`{ a\|\"b\" | data`))
+
+		out = gc.Val(1, gc.WithLabel(`</script><b>`))
+		var b strings.Builder
+		Expect(viz.WriteHTML(&b, out)).To(Succeed())
+		Expect(b.String()).ToNot(ContainSubstring(`</script><b>`))
+	})
+})
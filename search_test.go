@@ -0,0 +1,219 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/cli"
+	"github.com/richiejp/micrograd/internal/config"
+	"github.com/richiejp/micrograd/internal/search"
+)
+
+var _ = Describe("Hyperparameter search", func() {
+	var base config.Experiment
+
+	BeforeEach(func() {
+		base = config.Default()
+		base.Dataset.Samples = 40
+		base.Dataset.Val = 0.25
+		base.Model.Hidden = []uint{4}
+		base.Schedule.Epochs = 9
+	})
+
+	It("Lays out grids and samples ranges", func() {
+		space := search.Space{Params: []search.Param{
+			{Name: "optimizer.learning_rate", Min: 0.01, Max: 1, Log: true, Steps: 3},
+			{Name: "model.hidden", Values: []any{[]any{8}, []any{8, 8}}},
+		}}
+
+		grid := must(space.Grid())
+		Expect(grid).To(HaveLen(6))
+		Expect(grid[0][0].Value).To(BeNumerically("~", 0.01))
+		Expect(grid[2][0].Value).To(BeNumerically("~", 0.1))
+		Expect(grid[5][0].Value).To(BeNumerically("~", 1))
+		Expect(grid[5][1].String()).To(Equal("model.hidden=8,8"))
+
+		samples := space.Sample(20, 1)
+		Expect(samples).To(HaveLen(20))
+		Expect(space.Sample(20, 1)).To(Equal(samples))
+		for _, s := range samples {
+			Expect(s[0].Value).To(And(BeNumerically(">=", 0.01), BeNumerically("<=", 1)))
+		}
+
+		e := must(search.Apply(base, grid[5]))
+		Expect(e.Optimizer.LearningRate).To(BeNumerically("~", 1))
+		Expect(e.Model.Hidden).To(Equal([]uint{8, 8}))
+		Expect(base.Model.Hidden).To(Equal([]uint{4}))
+
+		_, err := search.Apply(base, []search.Assignment{{Name: "optimizer.momentum", Value: 0.9}})
+		Expect(err).To(MatchError(ContainSubstring("momentum")))
+		_, err = search.Apply(base, []search.Assignment{{Name: "model.seed.low", Value: 1}})
+		Expect(err).To(HaveOccurred())
+
+		bad := search.Space{Params: []search.Param{{Name: "loss.l2"}, {Min: 1, Max: 0.5}}}
+		Expect(bad.Validate()).To(MatchError(And(
+			ContainSubstring("loss.l2: values or a range are needed"),
+			ContainSubstring("params[1]: a name is needed"),
+			ContainSubstring("min 1 is above max 0.5"),
+		)))
+	})
+
+	It("Ranks a grid by validation metric", func() {
+		space := search.Space{Params: []search.Param{
+			{Name: "optimizer.learning_rate", Values: []any{0.0001, 0.5}},
+			{Name: "model.seed", Values: []any{1, 2}},
+		}}
+
+		var finished int
+		res := must(search.Run(base, space, search.Options{
+			Parallel: 3,
+			OnTrial:  func(search.Trial) { finished++ },
+		}))
+
+		Expect(finished).To(Equal(4))
+		Expect(res.Trials).To(HaveLen(4))
+		Expect(res.Params).To(Equal([]string{"optimizer.learning_rate", "model.seed"}))
+		for i, t := range res.Trials {
+			Expect(t.Err).ToNot(HaveOccurred())
+			if i > 0 {
+				Expect(t.Val.Accuracy).To(BeNumerically("<=", res.Trials[i-1].Val.Accuracy))
+			}
+		}
+
+		best, ok := res.Best()
+		Expect(ok).To(BeTrue())
+		Expect(best.Experiment.Optimizer.LearningRate).To(Equal(0.5))
+
+		var table strings.Builder
+		Expect(res.WriteTable(&table, 2)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+		Expect(lines).To(HaveLen(3))
+		Expect(strings.Fields(lines[0])).To(ContainElements("optimizer.learning_rate", "val_accuracy"))
+
+		var csv strings.Builder
+		Expect(res.WriteCSV(&csv)).To(Succeed())
+		Expect(strings.Count(csv.String(), "\n")).To(Equal(5))
+	})
+
+	It("Halves the configurations while raising the epochs", func() {
+		space := search.Space{Params: []search.Param{
+			{Name: "optimizer.learning_rate", Min: 0.001, Max: 1, Log: true},
+		}}
+
+		res := must(search.Run(base, space, search.Options{Strategy: search.Halving, Trials: 9, Seed: 3}))
+
+		perRound := map[int][]int{}
+		for _, t := range res.Trials {
+			Expect(t.Err).ToNot(HaveOccurred())
+			perRound[t.Round] = append(perRound[t.Round], t.Experiment.Schedule.Epochs)
+		}
+		Expect(perRound[0]).To(HaveLen(9))
+		Expect(perRound[1]).To(HaveLen(3))
+		Expect(perRound[2]).To(Equal([]int{9}))
+		Expect(perRound[0][0]).To(Equal(1))
+		Expect(perRound[1][0]).To(Equal(3))
+
+		best, _ := res.Best()
+		Expect(best.Round).To(Equal(2))
+
+		_, err := search.Run(base, search.Space{Params: []search.Param{
+			{Name: "schedule.epochs", Values: []any{1, 2}},
+		}}, search.Options{Strategy: search.Halving})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Ranks diverged trials with the failures", func() {
+		base.Optimizer.ClipNorm = 0
+		space := search.Space{Params: []search.Param{
+			{Name: "optimizer.learning_rate", Values: []any{0.5, 1e300}},
+		}}
+
+		for _, metric := range []search.Metric{search.Loss, search.Accuracy} {
+			res := must(search.Run(base, space, search.Options{Metric: metric}))
+
+			Expect(res.Trials).To(HaveLen(2))
+			Expect(res.Trials[0].Err).ToNot(HaveOccurred())
+			Expect(res.Trials[0].Experiment.Optimizer.LearningRate).To(Equal(0.5))
+			Expect(res.Trials[1].Err).To(MatchError(search.ErrDiverged))
+		}
+
+		res := must(search.Run(base, search.Space{Params: []search.Param{
+			{Name: "optimizer.learning_rate", Values: []any{1e300, 0.5, 1e300}},
+		}}, search.Options{Strategy: search.Halving, Trials: 9, Seed: 1}))
+		best, ok := res.Best()
+		Expect(ok).To(BeTrue())
+		Expect(best.Experiment.Optimizer.LearningRate).To(Equal(0.5))
+	})
+
+	It("Needs a validation set", func() {
+		base.Dataset.Val = 0
+		_, err := search.Run(base, search.Space{}, search.Options{})
+		Expect(err).To(MatchError(search.ErrNoValidation))
+	})
+
+	It("Loads the example spaces", func() {
+		paths, err := filepath.Glob("experiments/spaces/*.yaml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).ToNot(BeEmpty())
+
+		for _, path := range paths {
+			space := must(search.LoadSpace(path))
+			for _, c := range must(space.Grid()) {
+				_, err := search.Apply(base, c)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+
+		_, err = search.ReadSpace(strings.NewReader("params:\n  - name: loss.l2\n    value: 1\n"), config.YAML)
+		Expect(err).To(MatchError(ContainSubstring("value")))
+	})
+
+	It("Runs from the CLI", func() {
+		dir := GinkgoT().TempDir()
+		spacePath := filepath.Join(dir, "space.json")
+		out := filepath.Join(dir, "results", "search.csv")
+		best := filepath.Join(dir, "best.yaml")
+
+		Expect(os.WriteFile(spacePath, []byte(`{"params": [{"name": "loss.l2", "values": [0, 0.001]}]}`), 0o644)).To(Succeed())
+
+		var stdout, stderr strings.Builder
+		err := cli.Run([]string{"search",
+			"-space", spacePath,
+			"-samples", "30",
+			"-hidden", "4",
+			"-epochs", "3",
+			"-strategy", "random",
+			"-trials", "3",
+			"-o", out,
+			"-best", best,
+		}, nil, &stdout, &stderr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout.String()).To(ContainSubstring("Trial 2 round 0, 3 epochs, loss.l2="))
+		Expect(stdout.String()).To(ContainSubstring("Saved " + out))
+
+		b, err := os.ReadFile(out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Count(string(b), "\n")).To(Equal(4))
+
+		e := must(config.Load(best))
+		Expect(e.Model.Hidden).To(Equal([]uint{4}))
+		Expect(e.Schedule.Epochs).To(Equal(3))
+
+		stderr.Reset()
+		err = cli.Run([]string{"search", "-space", spacePath, "-strategy", "bayes"}, nil, &stdout, &stderr)
+		Expect(err).To(MatchError(cli.ErrUsage))
+		Expect(stderr.String()).To(ContainSubstring(`unknown strategy "bayes"`))
+
+		// Failures after the arguments are checked are not usage errors
+		stderr.Reset()
+		missing := filepath.Join(dir, "missing.csv")
+		err = cli.Run([]string{"search", "-space", spacePath, "-data", missing}, nil, &stdout, &stderr)
+		Expect(err).To(MatchError(ContainSubstring("missing.csv")))
+		Expect(err).ToNot(MatchError(cli.ErrUsage))
+		Expect(stderr.String()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,64 @@
+package main_test
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("Softmax", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+	})
+
+	It("Produces probabilities", func() {
+		probs := gc.Softmax(gc.Vals(1, 2, 3, 1000))
+
+		sum := 0.0
+		for _, p := range probs {
+			Expect(math.IsNaN(p.Data())).To(BeFalse())
+			sum += p.Data()
+		}
+		Expect(sum).To(BeNumerically("~", 1))
+		Expect(grad.Argmax(probs)).To(Equal(3))
+	})
+
+	It("Has the cross-entropy gradient p - onehot", func() {
+		logits := gc.Vals(0.5, -1, 2)
+		loss := gc.CrossEntropy(logits, 1)
+		gc.Backward(loss)
+
+		probs := gc.Softmax(gc.Vals(0.5, -1, 2))
+		Expect(loss.Data()).To(BeNumerically("~", -math.Log(probs[1].Data())))
+		Expect(logits[0].Grad()).To(BeNumerically("~", probs[0].Data()))
+		Expect(logits[1].Grad()).To(BeNumerically("~", probs[1].Data()-1))
+		Expect(logits[2].Grad()).To(BeNumerically("~", probs[2].Data()))
+	})
+
+	It("Can be used as a module", func() {
+		s := gc.Seq(gc.Lay(2, 3), grad.Softmax[float64]{})
+		out := s.Forward(gc.Vals(1, 2))
+
+		Expect(out).To(HaveLen(3))
+		Expect(out[0].Data() + out[1].Data() + out[2].Data()).To(BeNumerically("~", 1))
+	})
+
+	It("Can generate blobs", func() {
+		X, y, err := data.MakeBlobs(31, [][]float64{{0, 0}, {10, 10}, {-10, 10}}, 0.1, 7)
+		Expect(err).ToNot(HaveOccurred())
+		X2, y2, err := data.MakeBlobs(31, [][]float64{{0, 0}, {10, 10}, {-10, 10}}, 0.1, 7)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(X).To(Equal(X2))
+		Expect(y).To(Equal(y2))
+		Expect(y).To(ContainElements(0, 1, 2))
+		for i := range X {
+			Expect(X[i][0]).To(BeNumerically("~", []float64{0, 10, -10}[y[i]], 1))
+		}
+	})
+})
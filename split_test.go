@@ -0,0 +1,123 @@
+package main_test
+
+import (
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/data"
+	"github.com/richiejp/micrograd/internal/train"
+)
+
+var _ = Describe("Splitting", func() {
+	y := make([]int, 100)
+	for i := range y {
+		if i >= 80 {
+			y[i] = 1
+		}
+	}
+
+	count := func(idx []int, class int) int {
+		n := 0
+		for _, i := range idx {
+			if y[i] == class {
+				n++
+			}
+		}
+		return n
+	}
+
+	It("Splits with stratification", func() {
+		trainIdx, valIdx, testIdx, err := data.TrainValTestSplit(y, 0.1, 0.2, true, 1)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(trainIdx).To(HaveLen(70))
+		Expect(valIdx).To(HaveLen(10))
+		Expect(testIdx).To(HaveLen(20))
+		Expect(count(testIdx, 1)).To(Equal(4))
+		Expect(count(valIdx, 1)).To(Equal(2))
+
+		all := slices.Concat(trainIdx, valIdx, testIdx)
+		slices.Sort(all)
+		Expect(slices.Compact(all)).To(HaveLen(100))
+
+		again, _, _, _ := data.TrainValTestSplit(y, 0.1, 0.2, true, 1)
+		Expect(again).To(Equal(trainIdx))
+	})
+
+	It("Rejects fractions above one", func() {
+		_, _, err := data.TrainTestSplit(y, 1.5, false, 1)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Makes stratified folds", func() {
+		folds, err := data.KFold(y, 5, true, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(folds).To(HaveLen(5))
+
+		var tests []int
+		for _, f := range folds {
+			Expect(f.Test).To(HaveLen(20))
+			Expect(f.Train).To(HaveLen(80))
+			Expect(count(f.Test, 1)).To(Equal(4))
+			tests = append(tests, f.Test...)
+		}
+		slices.Sort(tests)
+		Expect(slices.Compact(tests)).To(HaveLen(100))
+
+		_, err = data.KFold(y, 1, true, 1)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Cross-validates a classifier", func() {
+		X, y, err := data.MakeBlobs(60, [][]float64{{-3, 0}, {3, 0}}, 0.5, 1)
+		Expect(err).ToNot(HaveOccurred())
+		folds, err := data.KFold(y, 3, true, 1)
+		Expect(err).ToNot(HaveOccurred())
+
+		var h train.History
+		res, err := train.CrossValidate(X, y, folds, train.Options{
+			Hidden:  []uint{4},
+			Steps:   20,
+			Seed:    1,
+			History: &h,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Folds).To(HaveLen(3))
+		Expect(res.Mean.Accuracy).To(BeNumerically(">", 0.9))
+		Expect(res.Std.Accuracy).To(BeNumerically("<", 0.1))
+
+		// Each fold has a history of its own
+		Expect(h.Steps).To(BeEmpty())
+		Expect(res.Histories).To(HaveLen(3))
+		for _, fh := range res.Histories {
+			Expect(fh.Steps).To(HaveLen(20))
+			Expect(fh.Steps[0].Step).To(Equal(0))
+		}
+	})
+
+	It("Needs at least two classes", func() {
+		for _, classes := range []int{-1, 0, 1} {
+			_, err := train.New(2, classes, train.Options{Hidden: []uint{2}})
+			Expect(err).To(MatchError(ContainSubstring("need at least 2 classes")))
+		}
+	})
+
+	It("Rejects labels outside the classes", func() {
+		X := [][]float64{{0, 1}, {1, 0}}
+
+		for _, classes := range []int{2, 3} {
+			c, err := train.New(2, classes, train.Options{Hidden: []uint{2}, Seed: 1})
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, bad := range []int{-1, classes} {
+				y := []int{0, bad}
+				Expect(c.Train(X, y, train.Options{Steps: 1})).To(MatchError(ContainSubstring("sample 1: label")))
+
+				_, err = c.Evaluate(X, y)
+				Expect(err).To(MatchError(ContainSubstring("sample 1: label")))
+			}
+		}
+	})
+})
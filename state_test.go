@@ -0,0 +1,74 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/richiejp/micrograd/internal/grad"
+)
+
+var _ = Describe("StateDict", func() {
+	var gc *grad.Context[float64]
+	BeforeEach(func() {
+		gc = &grad.Context[float64]{}
+	})
+
+	It("Names every parameter uniquely", func() {
+		n := gc.MLP(3, 4, 4, 1)
+
+		names := make(map[string]struct{})
+		count := 0
+		for name := range n.NamedParameters() {
+			names[name] = struct{}{}
+			count++
+		}
+
+		Expect(names).To(HaveLen(count))
+		Expect(names).To(HaveKey("layers.0.neurons.0.b"))
+		Expect(names).To(HaveKey("layers.1.neurons.3.w2"))
+		Expect(names).To(HaveKey("layers.2.neurons.0.w3"))
+	})
+
+	It("Can save and load a state dict", func() {
+		a := gc.MLP(2, 3, 1)
+		b := gc.MLP(2, 3, 1)
+
+		Expect(b.LoadStateDict(a.StateDict())).To(Succeed())
+		Expect(a.StateDict().Diff(b.StateDict(), 0)).To(BeEmpty())
+	})
+
+	It("Can partially load a state dict", func() {
+		n := gc.MLP(2, 3, 1)
+		before := n.StateDict()
+
+		Expect(n.LoadStateDict(grad.StateDict[float64]{"layers.1.neurons.0.b": 42})).To(Succeed())
+
+		diff := before.Diff(n.StateDict(), 0)
+		Expect(diff).To(HaveLen(1))
+		Expect(diff[0].Name).To(Equal("layers.1.neurons.0.b"))
+		Expect(diff[0].B).To(Equal(42.0))
+	})
+
+	It("Rejects unknown parameters", func() {
+		n := gc.MLP(2, 3, 1)
+		before := n.StateDict()
+
+		err := n.LoadStateDict(grad.StateDict[float64]{
+			"layers.0.neurons.0.b": 1,
+			"layers.9.neurons.0.b": 1,
+		})
+		Expect(err).To(MatchError(ContainSubstring("layers.9.neurons.0.b")))
+		Expect(before.Diff(n.StateDict(), 0)).To(BeEmpty())
+	})
+
+	It("Diffs models with different shapes", func() {
+		a := gc.MLP(2, 1).StateDict()
+		b := gc.MLP(3, 1).StateDict()
+
+		diff := a.Diff(b, 1e9)
+		Expect(diff).To(HaveLen(1))
+		Expect(diff[0].Name).To(Equal("layers.0.neurons.0.w2"))
+		Expect(diff[0].InA).To(BeFalse())
+		Expect(diff[0].InB).To(BeTrue())
+	})
+})